@@ -0,0 +1,153 @@
+package escpos
+
+// arabicForms are the presentation forms of an Arabic letter: isolated,
+// final, initial and medial. Letters that only join to the right (such as
+// alef) have no initial or medial forms.
+type arabicForms [4]rune
+
+const (
+	formIsolated = iota
+	formFinal
+	formInitial
+	formMedial
+)
+
+// joinsLeft returns true when the letter can join to the following letter.
+func (f arabicForms) joinsLeft() bool {
+	return f[formInitial] != 0
+}
+
+// arabicLetters are the presentation forms of the Arabic and Persian
+// letters.
+var arabicLetters = map[rune]arabicForms{
+	0x0621: {0xfe80, 0, 0, 0},                // hamza
+	0x0622: {0xfe81, 0xfe82, 0, 0},           // alef with madda above
+	0x0623: {0xfe83, 0xfe84, 0, 0},           // alef with hamza above
+	0x0624: {0xfe85, 0xfe86, 0, 0},           // waw with hamza above
+	0x0625: {0xfe87, 0xfe88, 0, 0},           // alef with hamza below
+	0x0626: {0xfe89, 0xfe8a, 0xfe8b, 0xfe8c}, // yeh with hamza above
+	0x0627: {0xfe8d, 0xfe8e, 0, 0},           // alef
+	0x0628: {0xfe8f, 0xfe90, 0xfe91, 0xfe92}, // beh
+	0x0629: {0xfe93, 0xfe94, 0, 0},           // teh marbuta
+	0x062a: {0xfe95, 0xfe96, 0xfe97, 0xfe98}, // teh
+	0x062b: {0xfe99, 0xfe9a, 0xfe9b, 0xfe9c}, // theh
+	0x062c: {0xfe9d, 0xfe9e, 0xfe9f, 0xfea0}, // jeem
+	0x062d: {0xfea1, 0xfea2, 0xfea3, 0xfea4}, // hah
+	0x062e: {0xfea5, 0xfea6, 0xfea7, 0xfea8}, // khah
+	0x062f: {0xfea9, 0xfeaa, 0, 0},           // dal
+	0x0630: {0xfeab, 0xfeac, 0, 0},           // thal
+	0x0631: {0xfead, 0xfeae, 0, 0},           // reh
+	0x0632: {0xfeaf, 0xfeb0, 0, 0},           // zain
+	0x0633: {0xfeb1, 0xfeb2, 0xfeb3, 0xfeb4}, // seen
+	0x0634: {0xfeb5, 0xfeb6, 0xfeb7, 0xfeb8}, // sheen
+	0x0635: {0xfeb9, 0xfeba, 0xfebb, 0xfebc}, // sad
+	0x0636: {0xfebd, 0xfebe, 0xfebf, 0xfec0}, // dad
+	0x0637: {0xfec1, 0xfec2, 0xfec3, 0xfec4}, // tah
+	0x0638: {0xfec5, 0xfec6, 0xfec7, 0xfec8}, // zah
+	0x0639: {0xfec9, 0xfeca, 0xfecb, 0xfecc}, // ain
+	0x063a: {0xfecd, 0xfece, 0xfecf, 0xfed0}, // ghain
+	0x0640: {0x0640, 0x0640, 0x0640, 0x0640}, // tatweel
+	0x0641: {0xfed1, 0xfed2, 0xfed3, 0xfed4}, // feh
+	0x0642: {0xfed5, 0xfed6, 0xfed7, 0xfed8}, // qaf
+	0x0643: {0xfed9, 0xfeda, 0xfedb, 0xfedc}, // kaf
+	0x0644: {0xfedd, 0xfede, 0xfedf, 0xfee0}, // lam
+	0x0645: {0xfee1, 0xfee2, 0xfee3, 0xfee4}, // meem
+	0x0646: {0xfee5, 0xfee6, 0xfee7, 0xfee8}, // noon
+	0x0647: {0xfee9, 0xfeea, 0xfeeb, 0xfeec}, // heh
+	0x0648: {0xfeed, 0xfeee, 0, 0},           // waw
+	0x0649: {0xfeef, 0xfef0, 0, 0},           // alef maksura
+	0x064a: {0xfef1, 0xfef2, 0xfef3, 0xfef4}, // yeh
+	0x067e: {0xfb56, 0xfb57, 0xfb58, 0xfb59}, // peh
+	0x0686: {0xfb7a, 0xfb7b, 0xfb7c, 0xfb7d}, // tcheh
+	0x0698: {0xfb8a, 0xfb8b, 0, 0},           // jeh
+	0x06a9: {0xfb8e, 0xfb8f, 0xfb90, 0xfb91}, // keheh
+	0x06af: {0xfb92, 0xfb93, 0xfb94, 0xfb95}, // gaf
+	0x06cc: {0xfbfc, 0xfbfd, 0xfbfe, 0xfbff}, // farsi yeh
+}
+
+// lamAlef are the isolated and final forms of the lam-alef ligatures, keyed
+// by the alef.
+var lamAlef = map[rune][2]rune{
+	0x0622: {0xfef5, 0xfef6},
+	0x0623: {0xfef7, 0xfef8},
+	0x0625: {0xfef9, 0xfefa},
+	0x0627: {0xfefb, 0xfefc},
+}
+
+// isArabicTransparent returns true for the Arabic combining marks (harakat),
+// which are skipped when determining how letters join.
+func isArabicTransparent(r rune) bool {
+	return (r >= 0x064b && r <= 0x065f) || r == 0x0670 || (r >= 0x06d6 && r <= 0x06ed)
+}
+
+// shapeArabic replaces the Arabic letters in s, in logical order, with their
+// contextual presentation forms and forms the lam-alef ligatures.
+func shapeArabic(s string) string {
+	in := []rune(s)
+	out := make([]rune, 0, len(in))
+
+	// neighbour returns the index of the next letter from i in direction d,
+	// skipping combining marks, or -1
+	neighbour := func(i, d int) int {
+		for i += d; i >= 0 && i < len(in); i += d {
+			if !isArabicTransparent(in[i]) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for i := 0; i < len(in); i++ {
+		forms, ok := arabicLetters[in[i]]
+		if !ok {
+			out = append(out, in[i])
+			continue
+		}
+
+		// joined to the previous letter when it can join to the left
+		prev := false
+		if j := neighbour(i, -1); j != -1 {
+			if f, ok := arabicLetters[in[j]]; ok && f.joinsLeft() {
+				prev = true
+			}
+		}
+
+		next := neighbour(i, 1)
+
+		// lam followed by alef forms a ligature
+		if in[i] == 0x0644 && next != -1 {
+			if lig, ok := lamAlef[in[next]]; ok {
+				if prev {
+					out = append(out, lig[1])
+				} else {
+					out = append(out, lig[0])
+				}
+				// keep any marks between the lam and the alef
+				out = append(out, in[i+1:next]...)
+				i = next
+				continue
+			}
+		}
+
+		// joined to the next letter when this one joins to the left and the
+		// next is a letter with a final form
+		joined := false
+		if next != -1 && forms.joinsLeft() {
+			f, ok := arabicLetters[in[next]]
+			joined = ok && f[formFinal] != 0
+		}
+
+		switch {
+		case prev && joined:
+			out = append(out, forms[formMedial])
+		case prev && forms[formFinal] != 0:
+			out = append(out, forms[formFinal])
+		case joined:
+			out = append(out, forms[formInitial])
+		default:
+			out = append(out, forms[formIsolated])
+		}
+	}
+
+	return string(out)
+}
@@ -0,0 +1,32 @@
+package escpos
+
+import (
+	"testing"
+)
+
+func TestShapeArabic(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"Initial medial final", "بيت", "ﺑﻴﺖ"},
+		{"Right joining alef", "باب", "ﺑﺎﺏ"},
+		{"Lam alef ligature", "سلام", "ﺳﻼﻡ"},
+		{"Isolated lam alef", "لا", "ﻻ"},
+		{"Harakat", "بَت", "ﺑَﺖ"},
+		{"Hamza", "ماء", "ﻣﺎﺀ"},
+		{"Letter before hamza", "بء", "ﺏﺀ"},
+		{"Persian", "پی", "ﭘﯽ"},
+		{"Separate words", "ب ب", "ﺏ ﺏ"},
+		{"Latin", "abc", "abc"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := shapeArabic(tc.text); got != tc.expected {
+				t.Errorf("Expected %+q, got %+q", tc.expected, got)
+			}
+		})
+	}
+}
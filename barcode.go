@@ -0,0 +1,612 @@
+package escpos
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BarcodeType is a barcode symbology, as used by GS k (function B).
+type BarcodeType byte
+
+// Barcode symbologies.
+const (
+	BarcodeUPCA                BarcodeType = 65
+	BarcodeUPCE                BarcodeType = 66
+	BarcodeEAN13               BarcodeType = 67
+	BarcodeEAN8                BarcodeType = 68
+	BarcodeCODE39              BarcodeType = 69
+	BarcodeITF                 BarcodeType = 70
+	BarcodeCODABAR             BarcodeType = 71
+	BarcodeCODE93              BarcodeType = 72
+	BarcodeCODE128             BarcodeType = 73
+	BarcodeGS1128              BarcodeType = 74
+	BarcodeGS1DataBarOmni      BarcodeType = 75
+	BarcodeGS1DataBarTruncated BarcodeType = 76
+	BarcodeGS1DataBarLimited   BarcodeType = 77
+	BarcodeGS1DataBarExpanded  BarcodeType = 78
+)
+
+// barcodeNames are the display names of the barcode symbologies.
+var barcodeNames = map[BarcodeType]string{
+	BarcodeUPCA:                "UPC-A",
+	BarcodeUPCE:                "UPC-E",
+	BarcodeEAN13:               "EAN13",
+	BarcodeEAN8:                "EAN8",
+	BarcodeCODE39:              "CODE39",
+	BarcodeITF:                 "ITF",
+	BarcodeCODABAR:             "CODABAR",
+	BarcodeCODE93:              "CODE93",
+	BarcodeCODE128:             "CODE128",
+	BarcodeGS1128:              "GS1-128",
+	BarcodeGS1DataBarOmni:      "GS1 DataBar Omnidirectional",
+	BarcodeGS1DataBarTruncated: "GS1 DataBar Truncated",
+	BarcodeGS1DataBarLimited:   "GS1 DataBar Limited",
+	BarcodeGS1DataBarExpanded:  "GS1 DataBar Expanded",
+}
+
+// String satisfies the fmt.Stringer interface.
+func (t BarcodeType) String() string {
+	if s, ok := barcodeNames[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("BarcodeType(%d)", byte(t))
+}
+
+// HRIPosition is the printing position of the human readable interpretation
+// (HRI) characters of a barcode, as used by GS H.
+type HRIPosition byte
+
+// HRI positions.
+const (
+	HRINone  HRIPosition = 0
+	HRIAbove HRIPosition = 1
+	HRIBelow HRIPosition = 2
+	HRIBoth  HRIPosition = 3
+)
+
+// HRIFont is the font of the human readable interpretation (HRI) characters
+// of a barcode, as used by GS f.
+type HRIFont byte
+
+// HRI fonts.
+const (
+	HRIFontA HRIFont = 0
+	HRIFontB HRIFont = 1
+	HRIFontC HRIFont = 2
+)
+
+// CodeSet is a CODE128 code set.
+type CodeSet byte
+
+// CODE128 code sets. CodeSetAuto selects the code sets automatically,
+// switching between them as needed to produce the shortest symbol.
+const (
+	CodeSetAuto CodeSet = 0
+	CodeSetA    CodeSet = 'A'
+	CodeSetB    CodeSet = 'B'
+	CodeSetC    CodeSet = 'C'
+)
+
+// BarcodeOptions are the options used when printing a barcode. Zero values
+// leave the corresponding printer setting unchanged, except for the HRI
+// position and font which are always sent.
+type BarcodeOptions struct {
+	// Height is the barcode height, in dots (1-255).
+	Height int
+
+	// Width is the module width, in dots (1-6).
+	Width int
+
+	// HRIPosition is the position of the HRI characters.
+	HRIPosition HRIPosition
+
+	// HRIFont is the font of the HRI characters.
+	HRIFont HRIFont
+
+	// CodeSet is the code set used for CODE128 barcodes.
+	CodeSet CodeSet
+}
+
+// BarcodeError is the error returned when barcode data is not valid for its
+// symbology.
+type BarcodeError struct {
+	Type   BarcodeType
+	Reason string
+}
+
+// Error satisfies the error interface.
+func (e *BarcodeError) Error() string {
+	return fmt.Sprintf("invalid %s barcode: %s", e.Type, e.Reason)
+}
+
+// SetBarcodeHeight sets the barcode height in dots (GS h).
+func (p *Printer) SetBarcodeHeight(height int) error {
+	if height < 1 || height > 255 {
+		return fmt.Errorf("invalid barcode height: %d", height)
+	}
+	return p.write([]byte{0x1d, 'h', byte(height)})
+}
+
+// SetBarcodeWidth sets the barcode module width in dots (GS w).
+func (p *Printer) SetBarcodeWidth(width int) error {
+	if width < 1 || width > 6 {
+		return fmt.Errorf("invalid barcode width: %d", width)
+	}
+	return p.write([]byte{0x1d, 'w', byte(width)})
+}
+
+// SetHRIPosition sets the printing position of the barcode HRI characters
+// (GS H).
+func (p *Printer) SetHRIPosition(pos HRIPosition) error {
+	if pos > HRIBoth {
+		return fmt.Errorf("invalid HRI position: %d", pos)
+	}
+	return p.write([]byte{0x1d, 'H', byte(pos)})
+}
+
+// SetHRIFont sets the font of the barcode HRI characters (GS f).
+func (p *Printer) SetHRIFont(font HRIFont) error {
+	if font > HRIFontC {
+		return fmt.Errorf("invalid HRI font: %d", font)
+	}
+	return p.write([]byte{0x1d, 'f', byte(font)})
+}
+
+// Barcode validates data for the barcode symbology typ, calculating check
+// digits as necessary, and sends the barcode to the printer.
+func (p *Printer) Barcode(typ BarcodeType, data string, opts *BarcodeOptions) error {
+	var o BarcodeOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	buf, err := encodeBarcode(typ, data, o.CodeSet)
+	if err != nil {
+		return err
+	}
+	return p.barcode(typ, buf, o)
+}
+
+// barcode sets the options o and sends the encoded barcode data buf.
+func (p *Printer) barcode(typ BarcodeType, buf []byte, o BarcodeOptions) error {
+	var err error
+	if o.Height != 0 {
+		if err = p.SetBarcodeHeight(o.Height); err != nil {
+			return err
+		}
+	}
+	if o.Width != 0 {
+		if err = p.SetBarcodeWidth(o.Width); err != nil {
+			return err
+		}
+	}
+	if err = p.SetHRIPosition(o.HRIPosition); err != nil {
+		return err
+	}
+	if err = p.SetHRIFont(o.HRIFont); err != nil {
+		return err
+	}
+
+	if !p.supports(FeatureBarcode) {
+		// function A, NUL terminated, only has the older symbologies
+		if typ > BarcodeCODABAR {
+			return p.unsupported(FeatureBarcode)
+		}
+		return p.write(append(append([]byte{0x1d, 'k', byte(typ - BarcodeUPCA)}, buf...), 0))
+	}
+	return p.write(append([]byte{0x1d, 'k', byte(typ), byte(len(buf))}, buf...))
+}
+
+// encodeBarcode validates data and returns the GS k data for the barcode
+// symbology typ.
+func encodeBarcode(typ BarcodeType, data string, set CodeSet) ([]byte, error) {
+	fail := func(format string, v ...interface{}) ([]byte, error) {
+		return nil, &BarcodeError{Type: typ, Reason: fmt.Sprintf(format, v...)}
+	}
+
+	var buf string
+	switch typ {
+	case BarcodeUPCA:
+		if !isDigits(data) || (len(data) != 11 && len(data) != 12) {
+			return fail("must be 11 or 12 digits")
+		}
+		buf = withCheckDigit(data, 11)
+		if len(data) > 11 && data != buf {
+			return fail("check digit mismatch")
+		}
+
+	case BarcodeUPCE:
+		if !isDigits(data) {
+			return fail("must be digits")
+		}
+		switch len(data) {
+		case 6:
+			buf = data
+		case 7, 8:
+			if data[0] != '0' {
+				return fail("number system must be 0")
+			}
+			// the check digit is that of the equivalent UPC-A
+			buf = data[:7] + string(gtinCheckDigit("0"+expandUPCE(data[1:7])))
+			if len(data) == 8 && data != buf {
+				return fail("check digit mismatch")
+			}
+		case 11, 12:
+			if data[0] != '0' {
+				return fail("number system must be 0")
+			}
+			buf = withCheckDigit(data, 11)
+			if len(data) == 12 && data != buf {
+				return fail("check digit mismatch")
+			}
+		default:
+			return fail("must be 6, 7, 8, 11 or 12 digits")
+		}
+
+	case BarcodeEAN13:
+		if !isDigits(data) || (len(data) != 12 && len(data) != 13) {
+			return fail("must be 12 or 13 digits")
+		}
+		buf = withCheckDigit(data, 12)
+		if len(data) > 12 && data != buf {
+			return fail("check digit mismatch")
+		}
+
+	case BarcodeEAN8:
+		if !isDigits(data) || (len(data) != 7 && len(data) != 8) {
+			return fail("must be 7 or 8 digits")
+		}
+		buf = withCheckDigit(data, 7)
+		if len(data) > 7 && data != buf {
+			return fail("check digit mismatch")
+		}
+
+	case BarcodeCODE39:
+		body := data
+		if strings.HasPrefix(body, "*") && strings.HasSuffix(body, "*") && len(body) >= 2 {
+			body = body[1 : len(body)-1]
+		}
+		if len(body) == 0 {
+			return fail("must not be empty")
+		}
+		for _, c := range body {
+			if !strings.ContainsRune(code39Chars, c) {
+				return fail("invalid character %q", c)
+			}
+		}
+		buf = data
+
+	case BarcodeITF:
+		if !isDigits(data) || len(data) < 2 || len(data)%2 != 0 {
+			return fail("must be an even number of digits")
+		}
+		buf = data
+
+	case BarcodeCODABAR:
+		if len(data) < 3 {
+			return fail("must have start, stop and at least one data character")
+		}
+		if !strings.ContainsRune(codabarStartStop, rune(data[0])) || !strings.ContainsRune(codabarStartStop, rune(data[len(data)-1])) {
+			return fail("must start and end with A, B, C or D")
+		}
+		for _, c := range data[1 : len(data)-1] {
+			if !strings.ContainsRune(codabarChars, c) {
+				return fail("invalid character %q", c)
+			}
+		}
+		buf = data
+
+	case BarcodeCODE93:
+		if len(data) == 0 {
+			return fail("must not be empty")
+		}
+		for _, c := range data {
+			if c > 127 {
+				return fail("invalid character %q", c)
+			}
+		}
+		buf = data
+
+	case BarcodeCODE128:
+		s, err := encodeCode128(data, set)
+		if err != nil {
+			return fail("%v", err)
+		}
+		buf = s
+
+	case BarcodeGS1128, BarcodeGS1DataBarExpanded:
+		if len(data) < 2 {
+			return fail("must be at least 2 characters")
+		}
+		for _, c := range data {
+			if c < 32 || c > 126 {
+				return fail("invalid character %q", c)
+			}
+		}
+		buf = data
+
+	case BarcodeGS1DataBarOmni, BarcodeGS1DataBarTruncated, BarcodeGS1DataBarLimited:
+		// the printer calculates the check digit of the GTIN itself
+		if !isDigits(data) || (len(data) != 13 && len(data) != 14) {
+			return fail("must be 13 or 14 digits")
+		}
+		if len(data) == 14 && withCheckDigit(data[:13], 13) != data {
+			return fail("check digit mismatch")
+		}
+		if typ == BarcodeGS1DataBarLimited && data[0] != '0' && data[0] != '1' {
+			return fail("first digit must be 0 or 1")
+		}
+		buf = data[:13]
+
+	default:
+		return fail("unsupported symbology")
+	}
+
+	if len(buf) > 255 {
+		return fail("data too long")
+	}
+
+	return []byte(buf), nil
+}
+
+const (
+	// code39Chars are the valid CODE39 data characters.
+	code39Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%+-./"
+
+	// codabarChars are the valid CODABAR data characters.
+	codabarChars = "0123456789$+-./:"
+
+	// codabarStartStop are the valid CODABAR start and stop characters.
+	codabarStartStop = "ABCDabcd"
+)
+
+// isDigits returns true when s is non-empty and consists only of the digits
+// 0-9.
+func isDigits(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// gtinCheckDigit calculates the GS1 (mod 10) check digit for digits.
+func gtinCheckDigit(digits string) byte {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// withCheckDigit returns the first n digits of data with the check digit
+// appended. When data already contains a check digit, it is replaced, so
+// callers can compare the result with data to validate it.
+func withCheckDigit(data string, n int) string {
+	return data[:n] + string(gtinCheckDigit(data[:n]))
+}
+
+// expandUPCE expands the 6 digit UPC-E body into the 10 digit manufacturer
+// and product code of the equivalent UPC-A.
+func expandUPCE(e string) string {
+	switch e[5] {
+	case '0', '1', '2':
+		return e[0:2] + string(e[5]) + "0000" + e[2:5]
+	case '3':
+		return e[0:3] + "00000" + e[3:5]
+	case '4':
+		return e[0:4] + "00000" + e[4:5]
+	default:
+		return e[0:5] + "0000" + e[5:6]
+	}
+}
+
+// encodeCode128 returns the GS k data for a CODE128 barcode, prefixing the
+// code set selection characters and escaping '{' as required by the printer.
+func encodeCode128(data string, set CodeSet) (string, error) {
+	if len(data) == 0 {
+		return "", fmt.Errorf("must not be empty")
+	}
+	for i := 0; i < len(data); i++ {
+		if data[i] > 127 {
+			return "", fmt.Errorf("invalid character %q", data[i])
+		}
+	}
+
+	switch set {
+	case CodeSetA, CodeSetB:
+		var b strings.Builder
+		b.WriteString("{" + string(set))
+		for i := 0; i < len(data); i++ {
+			if !code128Encodable(set, data[i]) {
+				return "", fmt.Errorf("character %q not in code set %c", data[i], set)
+			}
+			if data[i] == '{' {
+				b.WriteByte('{')
+			}
+			b.WriteByte(data[i])
+		}
+		return b.String(), nil
+
+	case CodeSetC:
+		if !isDigits(data) || len(data)%2 != 0 {
+			return "", fmt.Errorf("code set C requires an even number of digits")
+		}
+		var b strings.Builder
+		b.WriteString("{C")
+		for i := 0; i < len(data); i += 2 {
+			b.WriteByte((data[i]-'0')*10 + data[i+1] - '0')
+		}
+		return b.String(), nil
+
+	case CodeSetAuto:
+		return encodeCode128Auto(data), nil
+	}
+
+	return "", fmt.Errorf("invalid code set %d", set)
+}
+
+// code128Encodable returns true when c can be encoded in code set A or B.
+func code128Encodable(set CodeSet, c byte) bool {
+	if set == CodeSetA {
+		return c < 96
+	}
+	return c >= 32 && c < 128
+}
+
+// digitRun returns the number of consecutive digits in data starting at i.
+func digitRun(data string, i int) int {
+	n := 0
+	for i+n < len(data) && data[i+n] >= '0' && data[i+n] <= '9' {
+		n++
+	}
+	return n
+}
+
+// encodeCode128Auto encodes data for CODE128, switching between code sets to
+// keep the symbol short: runs of 4 or more digits (or 2 or more making up the
+// whole of data) are packed in code set C, everything else uses code set B,
+// or code set A for control characters.
+func encodeCode128Auto(data string) string {
+	// choose set A or B for the character at i
+	textSet := func(i int) CodeSet {
+		for ; i < len(data); i++ {
+			switch {
+			case data[i] < 32:
+				return CodeSetA
+			case data[i] >= 96:
+				return CodeSetB
+			}
+		}
+		return CodeSetB
+	}
+
+	var b strings.Builder
+	var cur CodeSet
+	for i := 0; i < len(data); {
+		run := digitRun(data, i)
+		if run >= 4 || (i == 0 && run == len(data) && run >= 2) {
+			// when the run has an odd length, the first digit is emitted in
+			// the current set, or the following one if at the start
+			if run%2 != 0 {
+				if cur == CodeSetAuto {
+					cur = textSet(i)
+					b.WriteString("{" + string(cur))
+				}
+				b.WriteByte(data[i])
+				i, run = i+1, run-1
+			}
+			if cur != CodeSetC {
+				cur = CodeSetC
+				b.WriteString("{C")
+			}
+			for ; run > 0; run, i = run-2, i+2 {
+				b.WriteByte((data[i]-'0')*10 + data[i+1] - '0')
+			}
+			continue
+		}
+
+		if cur == CodeSetAuto || cur == CodeSetC || !code128Encodable(cur, data[i]) {
+			cur = textSet(i)
+			b.WriteString("{" + string(cur))
+		}
+		if data[i] == '{' {
+			b.WriteByte('{')
+		}
+		b.WriteByte(data[i])
+		i++
+	}
+
+	return b.String()
+}
+
+// barcodeTypes are the ePOS-Print barcode type names.
+var barcodeTypes = map[string]BarcodeType{
+	"upc_a":                       BarcodeUPCA,
+	"upc_e":                       BarcodeUPCE,
+	"ean13":                       BarcodeEAN13,
+	"jan13":                       BarcodeEAN13,
+	"ean8":                        BarcodeEAN8,
+	"jan8":                        BarcodeEAN8,
+	"code39":                      BarcodeCODE39,
+	"itf":                         BarcodeITF,
+	"codabar":                     BarcodeCODABAR,
+	"code93":                      BarcodeCODE93,
+	"code128":                     BarcodeCODE128,
+	"gs1_128":                     BarcodeGS1128,
+	"gs1_databar_omnidirectional": BarcodeGS1DataBarOmni,
+	"gs1_databar_truncated":       BarcodeGS1DataBarTruncated,
+	"gs1_databar_limited":         BarcodeGS1DataBarLimited,
+	"gs1_databar_expanded":        BarcodeGS1DataBarExpanded,
+}
+
+// hriPositions are the ePOS-Print HRI position names.
+var hriPositions = map[string]HRIPosition{
+	"none":  HRINone,
+	"above": HRIAbove,
+	"below": HRIBelow,
+	"both":  HRIBoth,
+}
+
+// hriFonts are the ePOS-Print HRI font names.
+var hriFonts = map[string]HRIFont{
+	"font_a": HRIFontA,
+	"font_b": HRIFontB,
+	"font_c": HRIFontC,
+}
+
+// writeBarcode prints the barcode of an ePOS barcode element. CODE128 data
+// starting with a code set selection ({A, {B or {C) is sent as is, as the
+// ePOS SDK expects.
+func (p *Printer) writeBarcode(params map[string]string, data string) error {
+	typ, ok := barcodeTypes[params["type"]]
+	if !ok {
+		return errors.New("unsupported barcode type: " + params["type"])
+	}
+
+	var o BarcodeOptions
+	if hri, ok := params["hri"]; ok {
+		if o.HRIPosition, ok = hriPositions[hri]; !ok {
+			return errors.New("invalid barcode hri: " + hri)
+		}
+	}
+	if font, ok := params["font"]; ok {
+		if o.HRIFont, ok = hriFonts[font]; !ok {
+			return errors.New("invalid barcode font: " + font)
+		}
+	}
+	for name, v := range map[string]*int{"width": &o.Width, "height": &o.Height} {
+		if s, ok := params[name]; ok {
+			i, err := strconv.Atoi(s)
+			if err != nil {
+				return err
+			}
+			*v = i
+		}
+	}
+
+	if align, ok := params["align"]; ok {
+		if err := p.SetAlign(align); err != nil {
+			return err
+		}
+	}
+
+	data = textReplacer.Replace(data)
+	if typ == BarcodeCODE128 && len(data) > 2 && data[0] == '{' && strings.IndexByte("ABC", data[1]) >= 0 {
+		if len(data) > 255 {
+			return &BarcodeError{Type: typ, Reason: "data too long"}
+		}
+		return p.barcode(typ, []byte(data), o)
+	}
+	return p.Barcode(typ, data, &o)
+}
@@ -0,0 +1,85 @@
+package escpos
+
+import (
+	"testing"
+)
+
+func TestEncodeBarcode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		typ      BarcodeType
+		data     string
+		set      CodeSet
+		expected string
+		invalid  bool
+	}{
+		{"UPC-A check digit", BarcodeUPCA, "03600029145", 0, "036000291452", false},
+		{"UPC-A valid", BarcodeUPCA, "036000291452", 0, "036000291452", false},
+		{"UPC-A bad check digit", BarcodeUPCA, "036000291453", 0, "", true},
+		{"UPC-A letters", BarcodeUPCA, "0360002914a", 0, "", true},
+		{"UPC-E body", BarcodeUPCE, "425261", 0, "425261", false},
+		{"UPC-E check digit", BarcodeUPCE, "0425261", 0, "04252614", false},
+		{"UPC-E bad number system", BarcodeUPCE, "1425261", 0, "", true},
+		{"EAN13 check digit", BarcodeEAN13, "400638133393", 0, "4006381333931", false},
+		{"EAN13 bad check digit", BarcodeEAN13, "4006381333932", 0, "", true},
+		{"EAN8 check digit", BarcodeEAN8, "9638507", 0, "96385074", false},
+		{"CODE39", BarcodeCODE39, "*ABC-123*", 0, "*ABC-123*", false},
+		{"CODE39 lowercase", BarcodeCODE39, "abc", 0, "", true},
+		{"ITF", BarcodeITF, "1234", 0, "1234", false},
+		{"ITF odd length", BarcodeITF, "123", 0, "", true},
+		{"CODABAR", BarcodeCODABAR, "A40156B", 0, "A40156B", false},
+		{"CODABAR no start", BarcodeCODABAR, "40156B", 0, "", true},
+		{"CODE93", BarcodeCODE93, "Test 93", 0, "Test 93", false},
+		{"CODE128 set B", BarcodeCODE128, "a{b", CodeSetB, "{Ba{{b", false},
+		{"CODE128 set C", BarcodeCODE128, "1234", CodeSetC, "{C\x0c\x22", false},
+		{"CODE128 set C odd", BarcodeCODE128, "123", CodeSetC, "", true},
+		{"CODE128 set A lowercase", BarcodeCODE128, "a", CodeSetA, "", true},
+		{"CODE128 auto digits", BarcodeCODE128, "123456", CodeSetAuto, "{C\x0c\x22\x38", false},
+		{"CODE128 auto mixed", BarcodeCODE128, "AB12345", CodeSetAuto, "{BAB1{C\x17\x2d", false},
+		{"CODE128 auto text", BarcodeCODE128, "ab12", CodeSetAuto, "{Bab12", false},
+		{"CODE128 auto control", BarcodeCODE128, "A\tb", CodeSetAuto, "{AA\t{Bb", false},
+		{"GS1-128", BarcodeGS1128, "(01)12345678901231", 0, "(01)12345678901231", false},
+		{"GS1 DataBar", BarcodeGS1DataBarOmni, "2001234567890", 0, "2001234567890", false},
+		{"GS1 DataBar check digit", BarcodeGS1DataBarOmni, "20012345678909", 0, "2001234567890", false},
+		{"GS1 DataBar Limited", BarcodeGS1DataBarLimited, "2001234567890", 0, "", true},
+		{"Unsupported", BarcodeType(1), "1", 0, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf, err := encodeBarcode(tc.typ, tc.data, tc.set)
+			if tc.invalid {
+				if _, ok := err.(*BarcodeError); !ok {
+					t.Errorf("Expected BarcodeError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(buf) != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, buf)
+			}
+		})
+	}
+}
+
+func TestBarcode(t *testing.T) {
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+
+	err := p.Barcode(BarcodeEAN8, "9638507", &BarcodeOptions{
+		Height:      80,
+		Width:       3,
+		HRIPosition: HRIBelow,
+		HRIFont:     HRIFontB,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "\x1dhP\x1dw\x03\x1dH\x02\x1df\x01\x1dkD\x0896385074"
+	if got := string(w.GetWritten()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
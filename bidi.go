@@ -0,0 +1,247 @@
+package escpos
+
+import (
+	"golang.org/x/text/unicode/bidi"
+)
+
+// bidiMirror are the characters mirrored when displayed right-to-left.
+var bidiMirror = map[rune]rune{
+	'(': ')', ')': '(',
+	'[': ']', ']': '[',
+	'{': '}', '}': '{',
+	'<': '>', '>': '<',
+	'«': '»', '»': '«',
+}
+
+// bidiClass returns the bidirectional class of r. Explicit formatting
+// characters are not supported and are treated as other neutrals.
+func bidiClass(r rune) bidi.Class {
+	props, _ := bidi.LookupRune(r)
+	switch c := props.Class(); c {
+	case bidi.LRE, bidi.LRO, bidi.RLE, bidi.RLO, bidi.PDF,
+		bidi.LRI, bidi.RLI, bidi.FSI, bidi.PDI:
+		return bidi.ON
+	default:
+		return c
+	}
+}
+
+// isRTL returns true when the first strong character of the paragraph s is
+// right-to-left (rules P2 and P3 of the Unicode Bidirectional Algorithm).
+func isRTL(s string) bool {
+	for _, r := range s {
+		switch bidiClass(r) {
+		case bidi.L:
+			return false
+		case bidi.R, bidi.AL:
+			return true
+		}
+	}
+	return false
+}
+
+// isNeutral returns true for the classes resolved by rules N1 and N2.
+func isNeutral(c bidi.Class) bool {
+	switch c {
+	case bidi.ON, bidi.WS, bidi.S, bidi.B, bidi.BN:
+		return true
+	}
+	return false
+}
+
+// bidiLevels resolves the embedding level of each rune of a single line
+// paragraph with the implicit rules (W1-W7, N1-N2, I1-I2, L1) of the Unicode
+// Bidirectional Algorithm.
+func bidiLevels(runes []rune, rtl bool) []int {
+	base, e := 0, bidi.L
+	if rtl {
+		base, e = 1, bidi.R
+	}
+
+	n := len(runes)
+	types := make([]bidi.Class, n)
+	for i, r := range runes {
+		types[i] = bidiClass(r)
+	}
+
+	// W1: non-spacing marks take the type of the previous character
+	for i := range types {
+		if types[i] == bidi.NSM {
+			if i == 0 {
+				types[i] = e
+			} else {
+				types[i] = types[i-1]
+			}
+		}
+	}
+
+	// W2: european numbers after arabic letters are arabic numbers, W3: arabic
+	// letters are right-to-left
+	last := e
+	for i, c := range types {
+		switch c {
+		case bidi.L, bidi.R, bidi.AL:
+			last = c
+		case bidi.EN:
+			if last == bidi.AL {
+				types[i] = bidi.AN
+			}
+		}
+	}
+	for i, c := range types {
+		if c == bidi.AL {
+			types[i] = bidi.R
+		}
+	}
+
+	// W4: a single separator between two numbers of the same type
+	for i := 1; i < n-1; i++ {
+		prev, next := types[i-1], types[i+1]
+		switch {
+		case types[i] == bidi.ES && prev == bidi.EN && next == bidi.EN:
+			types[i] = bidi.EN
+		case types[i] == bidi.CS && prev == next && (prev == bidi.EN || prev == bidi.AN):
+			types[i] = prev
+		}
+	}
+
+	// W5: terminators adjacent to european numbers
+	for i := 0; i < n; i++ {
+		if types[i] != bidi.ET {
+			continue
+		}
+		j := i
+		for j < n && types[j] == bidi.ET {
+			j++
+		}
+		if (i > 0 && types[i-1] == bidi.EN) || (j < n && types[j] == bidi.EN) {
+			for k := i; k < j; k++ {
+				types[k] = bidi.EN
+			}
+		}
+		i = j
+	}
+
+	// W6: remaining separators and terminators are neutral
+	for i, c := range types {
+		if c == bidi.ES || c == bidi.ET || c == bidi.CS {
+			types[i] = bidi.ON
+		}
+	}
+
+	// W7: european numbers after left-to-right text are left-to-right
+	last = e
+	for i, c := range types {
+		switch c {
+		case bidi.L, bidi.R:
+			last = c
+		case bidi.EN:
+			if last == bidi.L {
+				types[i] = bidi.L
+			}
+		}
+	}
+
+	// N1, N2: neutrals take the direction of the surrounding text when both
+	// sides agree, and the embedding direction otherwise
+	strong := func(c bidi.Class) bidi.Class {
+		if c == bidi.EN || c == bidi.AN {
+			return bidi.R
+		}
+		return c
+	}
+	for i := 0; i < n; i++ {
+		if !isNeutral(types[i]) {
+			continue
+		}
+		j := i
+		for j < n && isNeutral(types[j]) {
+			j++
+		}
+		before, after := e, e
+		if i > 0 {
+			before = strong(types[i-1])
+		}
+		if j < n {
+			after = strong(types[j])
+		}
+		dir := e
+		if before == after {
+			dir = before
+		}
+		for k := i; k < j; k++ {
+			types[k] = dir
+		}
+		i = j
+	}
+
+	// I1, I2: resolve the levels
+	levels := make([]int, n)
+	for i, c := range types {
+		switch {
+		case base == 0 && c == bidi.R:
+			levels[i] = 1
+		case base == 0 && (c == bidi.AN || c == bidi.EN):
+			levels[i] = 2
+		case base == 1 && (c == bidi.L || c == bidi.EN || c == bidi.AN):
+			levels[i] = 2
+		default:
+			levels[i] = base
+		}
+	}
+
+	// L1: trailing whitespace is reset to the paragraph level
+	for i := n - 1; i >= 0; i-- {
+		c := bidiClass(runes[i])
+		if c != bidi.WS && c != bidi.S && c != bidi.BN {
+			break
+		}
+		levels[i] = base
+	}
+
+	return levels
+}
+
+// visualLine returns the single line paragraph s in visual (left-to-right
+// display) order, using rule L2 to reverse the runs and L4 to mirror
+// characters in right-to-left runs.
+func visualLine(s string, rtl bool) string {
+	runes := []rune(s)
+	levels := bidiLevels(runes, rtl)
+
+	for i, r := range runes {
+		if levels[i]%2 == 1 {
+			if m, ok := bidiMirror[r]; ok {
+				runes[i] = m
+			}
+		}
+	}
+
+	max := 0
+	for _, l := range levels {
+		if l > max {
+			max = l
+		}
+	}
+
+	// reverse every run at or above each level, from the highest level down
+	// to the lowest odd level
+	for l := max; l >= 1; l-- {
+		for i := 0; i < len(runes); i++ {
+			if levels[i] < l {
+				continue
+			}
+			j := i
+			for j < len(runes) && levels[j] >= l {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				runes[a], runes[b] = runes[b], runes[a]
+				levels[a], levels[b] = levels[b], levels[a]
+			}
+			i = j
+		}
+	}
+
+	return string(runes)
+}
@@ -0,0 +1,32 @@
+package escpos
+
+import (
+	"testing"
+)
+
+func TestVisualLine(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		rtl      bool
+		expected string
+	}{
+		{"Latin", "abc def", false, "abc def"},
+		{"Hebrew", "אבג", true, "גבא"},
+		{"Embedded RTL", "abc אבג def", false, "abc גבא def"},
+		{"Numbers in RTL", "אבג 123", true, "123 גבא"},
+		{"Decimal number in RTL", "אבג 12.50", true, "12.50 גבא"},
+		{"Mirrored brackets", "א(ב)", true, "(ב)א"},
+		{"Arabic digits in LTR", "Price ٣٠ ريال", false, "Price لاير ٣٠"},
+		{"Latin in RTL", "ريال abc", true, "abc لاير"},
+		{"Trailing space", "אב ", true, " בא"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := visualLine(tc.text, tc.rtl); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -6,13 +6,16 @@ import (
 	"net/http"
 	"os"
 
-	"github.com/cloudinn/escpos"
+	"github.com/morezig/goescpos"
 )
 
 var (
 	flagListen   = flag.String("l", "127.0.22.8:80", "listen")
 	flagEndpoint = flag.String("endpoint", escpos.DefaultEndpoint, "endpoint")
 	flagPrinter  = flag.String("p", "", "path to printer")
+	flagStatus   = flag.Bool("status", false, "read the printer status after each job")
+	flagProfile  = flag.String("profile", "", "printer model profile")
+	flagTimeout  = flag.Duration("timeout", 0, "give up on jobs not yet sent to the printer in time")
 )
 
 func main() {
@@ -30,13 +33,28 @@ func main() {
 	defer f.Close()
 
 	// create printer
-	ep, err := escpos.NewPrinter(f)
+	var popts []escpos.PrinterOption
+	if *flagProfile != "" {
+		pr, ok := escpos.LookupProfile(*flagProfile)
+		if !ok {
+			log.Fatalf("unknown printer profile %q, known profiles: %v", *flagProfile, escpos.ProfileNames())
+		}
+		popts = append(popts, escpos.WithProfile(pr))
+	}
+	ep, err := escpos.NewPrinter(f, popts...)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	// create server
-	s, err := escpos.NewServer(ep)
+	var opts []escpos.ServerOption
+	if *flagStatus {
+		opts = append(opts, escpos.WithStatus())
+	}
+	if *flagTimeout > 0 {
+		opts = append(opts, escpos.WithJobTimeout(*flagTimeout))
+	}
+	s, err := escpos.NewPrinterServer(ep, opts...)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,226 @@
+package escpos
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// CodePage is a printer character code table, selected with ESC t.
+type CodePage struct {
+	// Name is the name of the code page.
+	Name string
+
+	// Table is the ESC t table number that selects the code page.
+	Table byte
+
+	// Encode returns the byte encoding r in the code page, and false when r
+	// is not available in the code page.
+	Encode func(r rune) (byte, bool)
+}
+
+// charmapEncoder returns an encoder for the code page cm.
+func charmapEncoder(cm *charmap.Charmap) func(rune) (byte, bool) {
+	return cm.EncodeRune
+}
+
+// tableEncoder returns an encoder for a code page given the runes of its
+// upper half (0x80-0xff). Zero entries are not mapped.
+func tableEncoder(upper *[128]rune) func(rune) (byte, bool) {
+	m := make(map[rune]byte, len(upper))
+	for i, r := range upper {
+		if r != 0 {
+			m[r] = byte(0x80 + i)
+		}
+	}
+	return func(r rune) (byte, bool) {
+		if r < 0x80 {
+			return byte(r), true
+		}
+		b, ok := m[r]
+		return b, ok
+	}
+}
+
+// Code pages supported by Epson compatible printers, with their ESC t table
+// numbers.
+var (
+	CP437      = &CodePage{"CP437", 0, charmapEncoder(charmap.CodePage437)}
+	Katakana   = &CodePage{"Katakana", 1, katakanaEncoder}
+	CP850      = &CodePage{"CP850", 2, charmapEncoder(charmap.CodePage850)}
+	CP860      = &CodePage{"CP860", 3, charmapEncoder(charmap.CodePage860)}
+	CP863      = &CodePage{"CP863", 4, charmapEncoder(charmap.CodePage863)}
+	CP865      = &CodePage{"CP865", 5, charmapEncoder(charmap.CodePage865)}
+	ISO8859_7  = &CodePage{"ISO-8859-7", 15, charmapEncoder(charmap.ISO8859_7)}
+	CP1252     = &CodePage{"CP1252", 16, charmapEncoder(charmap.Windows1252)}
+	CP866      = &CodePage{"CP866", 17, charmapEncoder(charmap.CodePage866)}
+	CP852      = &CodePage{"CP852", 18, charmapEncoder(charmap.CodePage852)}
+	CP858      = &CodePage{"CP858", 19, charmapEncoder(charmap.CodePage858)}
+	CP855      = &CodePage{"CP855", 34, charmapEncoder(charmap.CodePage855)}
+	CP862      = &CodePage{"CP862", 36, charmapEncoder(charmap.CodePage862)}
+	CP864      = &CodePage{"CP864", 37, tableEncoder(&cp864)}
+	ISO8859_2  = &CodePage{"ISO-8859-2", 39, charmapEncoder(charmap.ISO8859_2)}
+	ISO8859_15 = &CodePage{"ISO-8859-15", 40, charmapEncoder(charmap.ISO8859_15)}
+	CP1250     = &CodePage{"CP1250", 45, charmapEncoder(charmap.Windows1250)}
+	CP1251     = &CodePage{"CP1251", 46, charmapEncoder(charmap.Windows1251)}
+	CP1253     = &CodePage{"CP1253", 47, charmapEncoder(charmap.Windows1253)}
+	CP1254     = &CodePage{"CP1254", 48, charmapEncoder(charmap.Windows1254)}
+	CP1255     = &CodePage{"CP1255", 49, charmapEncoder(charmap.Windows1255)}
+	CP1256     = &CodePage{"CP1256", 50, charmapEncoder(charmap.Windows1256)}
+	CP1257     = &CodePage{"CP1257", 51, charmapEncoder(charmap.Windows1257)}
+	CP1258     = &CodePage{"CP1258", 52, charmapEncoder(charmap.Windows1258)}
+)
+
+// CodePages are the known code pages, in the order they are tried when
+// automatically switching code pages.
+var CodePages = []*CodePage{
+	CP437, CP858, CP1252, CP850, CP852, CP1250, CP866, CP1251, CP855,
+	ISO8859_2, ISO8859_15, ISO8859_7, CP1253, CP1254, CP1257, CP1258,
+	CP860, CP863, CP865, CP862, CP1255, CP864, CP1256, Katakana,
+}
+
+// katakanaEncoder encodes half-width katakana (JIS X 0201).
+func katakanaEncoder(r rune) (byte, bool) {
+	switch {
+	case r < 0x80:
+		return byte(r), true
+	case r >= 0xff61 && r <= 0xff9f:
+		return byte(r - 0xff61 + 0xa1), true
+	}
+	return 0, false
+}
+
+// cp864 is the upper half of IBM code page 864 (Arabic). Arabic letters are
+// only available as presentation forms, so text must be shaped before it can
+// be encoded.
+var cp864 = [128]rune{
+	0x00b0, 0x00b7, 0x2219, 0x221a, 0x2592, 0x2500, 0x2502, 0x253c,
+	0x2524, 0x252c, 0x251c, 0x2534, 0x2510, 0x250c, 0x2514, 0x2518,
+	0x03b2, 0x221e, 0x03c6, 0x00b1, 0x00bd, 0x00bc, 0x2248, 0x00ab,
+	0x00bb, 0xfef7, 0xfef8, 0, 0, 0xfefb, 0xfefc, 0,
+	0x00a0, 0x00ad, 0xfe82, 0x00a3, 0x00a4, 0xfe84, 0, 0,
+	0xfe8e, 0xfe8f, 0xfe95, 0xfe99, 0x060c, 0xfe9d, 0xfea1, 0xfea5,
+	0x0660, 0x0661, 0x0662, 0x0663, 0x0664, 0x0665, 0x0666, 0x0667,
+	0x0668, 0x0669, 0xfed1, 0x061b, 0xfeb1, 0xfeb5, 0xfeb9, 0x061f,
+	0x00a2, 0xfe80, 0xfe81, 0xfe83, 0xfe85, 0xfeca, 0xfe8b, 0xfe8d,
+	0xfe91, 0xfe93, 0xfe97, 0xfe9b, 0xfe9f, 0xfea3, 0xfea7, 0xfea9,
+	0xfeab, 0xfead, 0xfeaf, 0xfeb3, 0xfeb7, 0xfebb, 0xfebf, 0xfec1,
+	0xfec5, 0xfecb, 0xfecf, 0x00a6, 0x00ac, 0x00f7, 0x00d7, 0xfec9,
+	0x0640, 0xfed3, 0xfed7, 0xfedb, 0xfedf, 0xfee3, 0xfee7, 0xfeeb,
+	0xfeed, 0xfeef, 0xfef3, 0xfebd, 0xfecc, 0xfece, 0xfecd, 0xfee1,
+	0xfe7d, 0x0651, 0xfee5, 0xfee9, 0xfeec, 0xfef0, 0xfef2, 0xfed0,
+	0xfed5, 0xfef5, 0xfef6, 0xfedd, 0xfed9, 0xfef1, 0x25a0, 0,
+}
+
+// defaultReplacement is the default replacement for unmappable runes.
+const defaultReplacement = '?'
+
+// encodeText transcodes the UTF-8 string s for the printer, starting with the
+// code page cur. Runes mapped to user-defined characters in user are printed
+// with the user-defined character set selected with ESC %. When a rune is not
+// available in the current code page, the first of the candidates that has it
+// is selected with ESC t. Runes that cannot be encoded are replaced with repl.
+// Returns the encoded text and the code page active at the end of it.
+func encodeText(s string, cur *CodePage, candidates []*CodePage, user map[rune]byte, repl byte) ([]byte, *CodePage) {
+	buf := make([]byte, 0, len(s))
+	inUser := false
+	for _, r := range s {
+		if c, ok := user[r]; ok {
+			if !inUser {
+				buf = append(buf, 0x1b, '%', 1)
+				inUser = true
+			}
+			buf = append(buf, c)
+			continue
+		}
+		if inUser {
+			buf = append(buf, 0x1b, '%', 0)
+			inUser = false
+		}
+
+		if r < utf8.RuneSelf {
+			buf = append(buf, byte(r))
+			continue
+		}
+
+		if b, ok := cur.Encode(r); ok {
+			buf = append(buf, b)
+			continue
+		}
+
+		found := false
+		for _, cp := range candidates {
+			if b, ok := cp.Encode(r); ok {
+				cur, found = cp, true
+				buf = append(buf, 0x1b, 't', cp.Table, b)
+				break
+			}
+		}
+		if !found {
+			buf = append(buf, repl)
+		}
+	}
+	if inUser {
+		buf = append(buf, 0x1b, '%', 0)
+	}
+
+	return buf, cur
+}
+
+// SetCodePage selects the character code table cp on the printer (ESC t).
+// Once a code page is selected, text written with Text and WriteText is
+// transcoded to it instead of being rendered as an image.
+func (p *Printer) SetCodePage(cp *CodePage) error {
+	if !p.supports(FeatureCodeTables) {
+		return p.unsupported(FeatureCodeTables)
+	}
+	if p.profile != nil && !p.profile.supportsCodePage(cp) {
+		return fmt.Errorf("%s does not support code page %s", p.profile.Name, cp.Name)
+	}
+	p.codePage = cp
+	return p.write([]byte{0x1b, 't', cp.Table})
+}
+
+// SetAutoCodePage enables automatic switching to the first of pages that has
+// a character not available in the current code page. Calling it without any
+// pages disables automatic switching. Pages not supported by the printer's
+// profile are left out.
+func (p *Printer) SetAutoCodePage(pages ...*CodePage) {
+	if p.profile != nil {
+		var supported []*CodePage
+		for _, cp := range pages {
+			if p.profile.Supports(FeatureCodeTables) && p.profile.supportsCodePage(cp) {
+				supported = append(supported, cp)
+			}
+		}
+		pages = supported
+	}
+	p.codePages = pages
+}
+
+// SetReplacementChar sets the character printed in place of runes that are
+// not available in any usable code page.
+func (p *Printer) SetReplacementChar(c byte) {
+	p.replacement = c
+}
+
+// WriteText transcodes the UTF-8 string s to the printer's code page and
+// writes it, switching code pages as needed when automatic switching is
+// enabled and printing user-defined characters inline. When no code page has
+// been selected, CP437 (the printer default) is assumed.
+func (p *Printer) WriteText(s string) (int, error) {
+	cur := p.codePage
+	if cur == nil {
+		cur = CP437
+	}
+
+	buf, cur := encodeText(s, cur, p.codePages, p.userChars, p.replacement)
+	n, err := p.Write(buf)
+	if err != nil {
+		return n, err
+	}
+
+	p.codePage = cur
+	return len(s), nil
+}
@@ -0,0 +1,60 @@
+package escpos
+
+import (
+	"testing"
+)
+
+func TestEncodeText(t *testing.T) {
+	testCases := []struct {
+		name       string
+		text       string
+		cur        *CodePage
+		candidates []*CodePage
+		expected   string
+		last       *CodePage
+	}{
+		{"ASCII", "Total: $25.00\n", CP437, nil, "Total: $25.00\n", CP437},
+		{"CP437", "Café", CP437, nil, "Caf\x82", CP437},
+		{"CP858 euro", "5€", CP858, nil, "5\xd5", CP858},
+		{"CP1252 euro", "5€", CP1252, nil, "5\x80", CP1252},
+		{"CP866", "Привет", CP866, nil, "\x8f\xe0\xa8\xa2\xa5\xe2", CP866},
+		{"CP864", "١ﺍ", CP864, nil, "\xb1\xc7", CP864},
+		{"Katakana", "ｱｲ", Katakana, nil, "\xb1\xb2", Katakana},
+		{"Unmappable", "a€b", CP437, nil, "a?b", CP437},
+		{"Auto switch", "é€Я", CP437, CodePages, "\x82\x1bt\x13\xd5\x1bt\x11\x9f", CP866},
+		{"Auto no match", "a中", CP437, CodePages, "a?", CP437},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf, last := encodeText(tc.text, tc.cur, tc.candidates, nil, '?')
+			if string(buf) != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, buf)
+			}
+			if last != tc.last {
+				t.Errorf("Expected code page %s, got %s", tc.last.Name, last.Name)
+			}
+		})
+	}
+}
+
+func TestPrinterCodePage(t *testing.T) {
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+
+	if err := p.SetCodePage(CP858); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	p.SetReplacementChar('*')
+	if err := p.Text(map[string]string{}, "€ 中"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := p.Init(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "\x1bt\x13\xd5 *\x1b@\x1bt\x13"
+	if got := string(w.GetWritten()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
@@ -0,0 +1,104 @@
+package escpos
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// Color is a print colour. Two colour printers usually print the first colour
+// in black and the second in red.
+type Color byte
+
+// Colors.
+const (
+	ColorFirst Color = iota
+	ColorSecond
+	ColorThird
+	ColorFourth
+
+	ColorBlack = ColorFirst
+	ColorRed   = ColorSecond
+)
+
+// graphicsColor returns the colour c as used by GS 8 L function 112.
+func graphicsColor(c Color) byte {
+	return '1' + byte(c)
+}
+
+// colors are the ePOS-Print colour names.
+var colors = map[string]Color{
+	"color_1": ColorFirst,
+	"color_2": ColorSecond,
+	"color_3": ColorThird,
+	"color_4": ColorFourth,
+}
+
+// SetColor selects the colour of the text (ESC r). Only the first and second
+// colours can be used for text.
+func (p *Printer) SetColor(c Color) error {
+	if c > ColorSecond {
+		return fmt.Errorf("unsupported text color: %d", c)
+	}
+	return p.write([]byte{0x1b, 'r', byte(c)})
+}
+
+// rasterSize returns the height of a raster of data packed in lines of
+// bytesWidth bytes.
+func rasterSize(data []byte, bytesWidth int) int {
+	if bytesWidth == 0 {
+		return 0
+	}
+	return len(data) / bytesWidth
+}
+
+// PrintImageColors prints the image planes black and red, converted to
+// rasters with opts, in the first and second colours of a two colour
+// printer. The planes can be split from a single image with
+// raster.SplitColors, and must be the same size. The image is always printed
+// as graphics (GS 8 L).
+func (p *Printer) PrintImageColors(black, red image.Image, opts *ImageOptions) error {
+	o := p.imageOptions(opts)
+
+	first, width, lineWidth := o.Converter.ToRaster(black)
+	second, secondWidth, _ := o.Converter.ToRaster(red)
+	if width != secondWidth || len(first) != len(second) {
+		return errors.New("color planes differ in size")
+	}
+
+	if err := p.SetAlign(o.Align); err != nil {
+		return err
+	}
+	return p.graphicsPlanes(toneMonochrome, ColorFirst, width, rasterSize(first, lineWidth), lineWidth, first, second)
+}
+
+// PrintImageTones prints img in four tones, converted to a raster with opts,
+// on printers supporting multiple tone graphics. The tones are sent as two
+// bit planes, with the least significant bit first. The image is always
+// printed as graphics (GS 8 L).
+func (p *Printer) PrintImageTones(img image.Image, opts *ImageOptions) error {
+	o := p.imageOptions(opts)
+
+	tones, width, height := o.Converter.ToTones(img, 4)
+
+	lineWidth := (width + 7) / 8
+	planes := [][]byte{
+		make([]byte, lineWidth*height),
+		make([]byte, lineWidth*height),
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			t := tones[y*width+x]
+			for i, plane := range planes {
+				if t&(1<<uint(i)) != 0 {
+					plane[y*lineWidth+x/8] |= 0x80 >> uint(x%8)
+				}
+			}
+		}
+	}
+
+	if err := p.SetAlign(o.Align); err != nil {
+		return err
+	}
+	return p.graphicsPlanes(toneMultiple, ColorFirst, width, height, lineWidth, planes...)
+}
@@ -0,0 +1,128 @@
+package escpos
+
+import (
+	"encoding/base64"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/morezig/goescpos/raster"
+)
+
+func TestSetColor(t *testing.T) {
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+	if err := p.SetColor(ColorRed); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := string(w.GetWritten()); got != "\x1br\x01" {
+		t.Errorf("Expected %q, got %q", "\x1br\x01", got)
+	}
+	if err := p.SetColor(ColorThird); err == nil {
+		t.Error("Expected error for third color")
+	}
+}
+
+// graphicsHeader returns the GS 8 L function 112 header of a width×height
+// plane.
+func graphicsHeader(tone, color byte, width, height, size int) string {
+	p := 10 + size
+	return string([]byte{
+		0x1d, '8', 'L', byte(p), byte(p >> 8), 0, 0, '0', 'p', tone, 1, 1, color,
+		byte(width), byte(width >> 8), byte(height), byte(height >> 8),
+	})
+}
+
+const graphicsPrint = "\x1d(L\x02\x0002"
+
+func TestPrintImageColors(t *testing.T) {
+	black := image.NewGray(image.Rect(0, 0, 8, 1))
+	red := image.NewGray(image.Rect(0, 0, 8, 1))
+	black.SetGray(0, 0, color.Gray{0xff})
+	red.SetGray(7, 0, color.Gray{0xff})
+
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+	if err := p.PrintImageColors(black, red, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "\x1ba\x01" +
+		graphicsHeader('0', '1', 8, 1, 1) + "\x80" +
+		graphicsHeader('0', '2', 8, 1, 1) + "\x01" +
+		graphicsPrint
+	if got := string(w.GetWritten()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	if err := p.PrintImageColors(black, image.NewGray(image.Rect(0, 0, 8, 2)), nil); err == nil {
+		t.Error("Expected error for planes of different sizes")
+	}
+}
+
+func TestPrintImageTones(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 1))
+	for x, v := range []uint8{0x00, 0x55, 0xaa, 0xff} {
+		img.SetGray(x, 0, color.Gray{v})
+	}
+
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+	if err := p.PrintImageTones(img, &ImageOptions{Align: "left"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "\x1ba\x00" +
+		graphicsHeader('4', '1', 4, 1, 1) + "\x50" +
+		graphicsHeader('4', '2', 4, 1, 1) + "\x30" +
+		graphicsPrint
+	if got := string(w.GetWritten()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestImageColor(t *testing.T) {
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+	params := map[string]string{"width": "8", "height": "2", "color": "color_2"}
+	if err := p.Image(params, base64.StdEncoding.EncodeToString([]byte{0xf0, 0x0f})); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := graphicsHeader('0', '2', 8, 2, 2) + "\xf0\x0f" + graphicsPrint
+	if got := string(w.GetWritten()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	if err := p.Image(params, base64.StdEncoding.EncodeToString([]byte{0xf0})); err == nil {
+		t.Error("Expected error for short image data")
+	}
+}
+
+func TestSplitColorsPrint(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 1))
+	for x := 0; x < 8; x++ {
+		img.Set(x, 0, color.White)
+	}
+	img.Set(0, 0, color.RGBA{0xff, 0x00, 0x00, 0xff})
+	img.Set(1, 0, color.RGBA{0x00, 0x00, 0x00, 0xff})
+
+	black, red := raster.SplitColors(img)
+	if black.GrayAt(0, 0).Y != 0 || red.GrayAt(0, 0).Y != 0xff {
+		t.Errorf("Expected red pixel in red plane only")
+	}
+	if black.GrayAt(1, 0).Y != 0xff || red.GrayAt(1, 0).Y != 0 {
+		t.Errorf("Expected black pixel in black plane only")
+	}
+
+	// the white background prints no dots in either colour
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+	if err := p.PrintImageColors(black, red, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "\x1ba\x01" +
+		graphicsHeader('0', '1', 8, 1, 1) + "\x40" +
+		graphicsHeader('0', '2', 8, 1, 1) + "\x80" +
+		graphicsPrint
+	if got := string(w.GetWritten()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
@@ -1,6 +1,7 @@
 package connection
 
 import (
+	"context"
 	"io"
 	"net"
 	"os"
@@ -12,13 +13,20 @@ import (
 //NewConnection creats a connection with a usb printer or a network printer and
 //returns an object to use escops package functions with
 func NewConnection(connectionType string, connectionHost string) (*escpos.Printer, error) {
+	return NewConnectionContext(context.Background(), connectionType, connectionHost)
+}
+
+//NewConnectionContext is like NewConnection, but gives up connecting to a
+//network printer when ctx is done
+func NewConnectionContext(ctx context.Context, connectionType string, connectionHost string) (*escpos.Printer, error) {
 	var f io.ReadWriter
 	var err error
 
 	if connectionType == "usb" {
 		f, err = os.OpenFile(connectionHost, os.O_WRONLY, 0)
 	} else if connectionType == "network" {
-		f, err = net.DialTimeout("tcp", connectionHost, 3*time.Second)
+		d := net.Dialer{Timeout: 3 * time.Second}
+		f, err = d.DialContext(ctx, "tcp", connectionHost)
 	}
 	if err != nil {
 		return nil, err
@@ -0,0 +1,174 @@
+package escpos
+
+import (
+	"context"
+	"image"
+	"net"
+	"time"
+
+	"github.com/morezig/goescpos/raster"
+)
+
+// TimeoutError is returned by printer operations that did not complete before
+// the deadline of their context, or of the printer connection.
+type TimeoutError struct {
+	// Op is the operation that timed out, "read" or "write".
+	Op string
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error satisfies the error interface.
+func (e *TimeoutError) Error() string {
+	return "printer " + e.Op + " timed out: " + e.Err.Error()
+}
+
+// Timeout satisfies the net.Error interface.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary satisfies the net.Error interface.
+func (e *TimeoutError) Temporary() bool {
+	return true
+}
+
+// Unwrap returns the underlying error.
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// readDeadliner is a connection supporting read deadlines, such as a
+// net.Conn or an *os.File.
+type readDeadliner interface {
+	SetReadDeadline(time.Time) error
+}
+
+// writeDeadliner is a connection supporting write deadlines, such as a
+// net.Conn or an *os.File.
+type writeDeadliner interface {
+	SetWriteDeadline(time.Time) error
+}
+
+// Do runs fn, which sends commands to the printer, with the printer I/O bound
+// to ctx. Once ctx is done, writes and reads fail, and the printer records
+// the error like any other write error. On connections supporting write
+// deadlines (a net.Conn, or an *os.File for a pipe or socket), the deadline of
+// ctx is applied and a write in progress is interrupted when ctx is done;
+// otherwise ctx is checked before each write, such as between the bands of a
+// raster image. Calls to Do from fn use the outer connection deadline.
+//
+// When ctx expires, the error is a *TimeoutError; when it is canceled, the
+// error is context.Canceled.
+func (p *Printer) Do(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return timeoutError(ctx, "write", err)
+	}
+
+	prev := p.ctx
+	p.ctx = ctx
+	defer func() { p.ctx = prev }()
+
+	if d, ok := p.w.(writeDeadliner); ok && prev == nil {
+		if restore, ok := setDeadline(ctx, d.SetWriteDeadline); ok {
+			defer restore()
+		}
+	}
+	return fn()
+}
+
+// WriteContext writes buf to the printer, giving up when ctx is done.
+func (p *Printer) WriteContext(ctx context.Context, buf []byte) (int, error) {
+	var n int
+	err := p.Do(ctx, func() error {
+		var err error
+		n, err = p.Write(buf)
+		return err
+	})
+	return n, err
+}
+
+// WriteNodesContext writes ePOS-Print nodes to the printer, giving up when
+// ctx is done.
+func (p *Printer) WriteNodesContext(ctx context.Context, nodes []Node) error {
+	return p.Do(ctx, func() error {
+		return p.WriteNodes(nodes)
+	})
+}
+
+// PrintImageDataContext prints an image, giving up when ctx is done.
+func (p *Printer) PrintImageDataContext(ctx context.Context, img image.Image, opts *ImageOptions) error {
+	return p.Do(ctx, func() error {
+		return p.PrintImageData(img, opts)
+	})
+}
+
+// RasterContext writes a raster to the printer, giving up when ctx is done,
+// even in between the bands of the raster.
+func (p *Printer) RasterContext(ctx context.Context, width, height, lineWidth int, imgBw []byte, printingType raster.PrintingType) error {
+	return p.Do(ctx, func() error {
+		return p.Raster(width, height, lineWidth, imgBw, printingType)
+	})
+}
+
+// writeContext writes buf to the printer, returning only the error, and
+// giving up when ctx is done.
+func (p *Printer) writeContext(ctx context.Context, buf []byte) error {
+	_, err := p.WriteContext(ctx, buf)
+	return err
+}
+
+// currentContext returns the context of the running Do call, if any.
+func (p *Printer) currentContext() context.Context {
+	if p.ctx != nil {
+		return p.ctx
+	}
+	return context.Background()
+}
+
+// setDeadline applies the deadline of ctx with set, and moves the deadline to
+// the past when ctx is done early, so that blocked I/O returns. It returns
+// false when the connection does not support deadlines, and otherwise a
+// function clearing the deadline.
+func setDeadline(ctx context.Context, set func(time.Time) error) (func(), bool) {
+	deadline, _ := ctx.Deadline()
+	if set(deadline) != nil {
+		return nil, false
+	}
+
+	stop, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			set(time.Unix(1, 0))
+		case <-stop:
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-exited
+		set(time.Time{})
+	}, true
+}
+
+// timeoutError returns the error of the operation op that failed with err
+// while ctx was in effect: context.Canceled when ctx was canceled, and a
+// *TimeoutError when ctx expired or the connection deadline passed.
+func timeoutError(ctx context.Context, op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*TimeoutError); ok {
+		return err
+	}
+	if ctx != nil && ctx.Err() == context.Canceled {
+		return context.Canceled
+	}
+	if ne, ok := err.(net.Error); err == context.DeadlineExceeded || ok && ne.Timeout() {
+		return &TimeoutError{Op: op, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+package escpos
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/morezig/goescpos/raster"
+)
+
+// cancelWriter cancels a context after a number of writes.
+type cancelWriter struct {
+	*MockWriter
+	writes int
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	if w.writes--; w.writes == 0 {
+		w.cancel()
+	}
+	return w.MockWriter.Write(p)
+}
+
+func TestDoCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := &cancelWriter{MockWriter: NewMockWriter(), writes: 1, cancel: cancel}
+	p, _ := NewPrinter(w)
+	p.SetRasterBandHeight(1)
+
+	// only the first band is sent
+	err := p.RasterContext(ctx, 8, 3, 1, []byte{1, 2, 3}, raster.BitImage)
+	if err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if expected := "\x1dv0\x00\x01\x00\x01\x00\x01"; string(w.GetWritten()) != expected {
+		t.Errorf("Expected %q, got %q", expected, w.GetWritten())
+	}
+	if p.Err() != context.Canceled {
+		t.Errorf("Expected sticky context.Canceled, got %v", p.Err())
+	}
+
+	// done contexts fail before writing
+	p.ClearErr()
+	if _, err := p.WriteContext(ctx, []byte("x")); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if p.Err() != nil {
+		t.Errorf("Unexpected error: %v", p.Err())
+	}
+}
+
+func TestDoTimeout(t *testing.T) {
+	// the printer never reads
+	conn, printer := net.Pipe()
+	defer conn.Close()
+	defer printer.Close()
+
+	p, _ := NewPrinter(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.WriteContext(ctx, []byte("hello"))
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Write took %v", d)
+	}
+	te, ok := err.(*TimeoutError)
+	if !ok || te.Op != "write" {
+		t.Fatalf("Expected write *TimeoutError, got %v", err)
+	}
+	if ne, ok := p.Err().(net.Error); !ok || !ne.Timeout() {
+		t.Errorf("Expected sticky timeout error, got %v", p.Err())
+	}
+
+	// the deadline is cleared afterwards
+	p.ClearErr()
+	go printer.Read(make([]byte, 1))
+	if err := p.write([]byte("x")); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestServerJobTimeout(t *testing.T) {
+	conn, printer := net.Pipe()
+	defer conn.Close()
+	defer printer.Close()
+
+	server, err := NewServer(conn, WithJobTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	body := `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>` +
+		`<epos-print xmlns="http://www.epson-pos.com/schemas/2011/03/epos-print"><cut/></epos-print>` +
+		`</s:Body></s:Envelope>`
+	req := httptest.NewRequest("POST", DefaultEndpoint, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if expected := `success="false" code="EX_TIMEOUT"`; !strings.Contains(w.Body.String(), expected) {
+		t.Errorf("Expected response containing %q, got %q", expected, w.Body.String())
+	}
+}
+
+func TestServerStatusClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the client goes away once the job is sent
+	w := &cancelWriter{MockWriter: NewMockWriter(), writes: 1, cancel: cancel}
+	w.buffer.WriteString("\x12\x12\x12\x12")
+	server, err := NewServer(w, WithStatus(), WithJobTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	body := `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>` +
+		`<epos-print xmlns="http://www.epson-pos.com/schemas/2011/03/epos-print"><cut/></epos-print>` +
+		`</s:Body></s:Envelope>`
+	req := httptest.NewRequest("POST", DefaultEndpoint, strings.NewReader(body)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	if expected := `success="true" code="" status="2"`; !strings.Contains(rec.Body.String(), expected) {
+		t.Errorf("Expected response containing %q, got %q", expected, rec.Body.String())
+	}
+}
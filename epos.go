@@ -0,0 +1,291 @@
+package escpos
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/morezig/goescpos/raster"
+)
+
+// LineStyle is the style of a ruled line.
+type LineStyle int
+
+// Line styles.
+const (
+	LineThin LineStyle = iota
+	LineMedium
+	LineThick
+	LineThinDouble
+	LineMediumDouble
+	LineThickDouble
+)
+
+// lineStyles are the ePOS-Print line style names.
+var lineStyles = map[string]LineStyle{
+	"thin":          LineThin,
+	"medium":        LineMedium,
+	"thick":         LineThick,
+	"thin_double":   LineThinDouble,
+	"medium_double": LineMediumDouble,
+	"thick_double":  LineThickDouble,
+}
+
+// rows returns the rows of a line in the style s, true for printed rows.
+func (s LineStyle) rows() []bool {
+	dots := int(s%3) + 1
+	rows := make([]bool, dots)
+	for i := range rows {
+		rows[i] = true
+	}
+	if s >= LineThinDouble {
+		rows = append(rows, make([]bool, dots)...)
+		rows = append(rows, rows[:dots]...)
+	}
+	return rows
+}
+
+// HorizontalLine prints a horizontal ruled line from dot x1 to dot x2, from
+// the left of the printable area, as a raster image in the mode supported by
+// the printer. The alignment is restored afterwards.
+func (p *Printer) HorizontalLine(x1, x2 int, style LineStyle) error {
+	if x1 < 0 || x2 < x1 || x2 > 0xffff {
+		return fmt.Errorf("invalid line from %d to %d", x1, x2)
+	}
+	if style < LineThin || style > LineThickDouble {
+		return fmt.Errorf("invalid line style: %d", style)
+	}
+
+	width := x2 + 1
+	lineWidth := (width + 7) / 8
+	rows := style.rows()
+	data := make([]byte, lineWidth*len(rows))
+	for y, on := range rows {
+		if !on {
+			continue
+		}
+		for x := x1; x <= x2; x++ {
+			data[y*lineWidth+x/8] |= 0x80 >> uint(x%8)
+		}
+	}
+
+	return p.withAlign("left", func() error {
+		return p.Raster(width, len(rows), lineWidth, data, raster.BitImage)
+	})
+}
+
+// lineStyle returns the style attribute of an ePOS line element.
+func lineStyle(params map[string]string) (LineStyle, error) {
+	s, ok := params["style"]
+	if !ok {
+		return LineThin, nil
+	}
+	style, ok := lineStyles[s]
+	if !ok {
+		return 0, fmt.Errorf("invalid line style: %q", s)
+	}
+	return style, nil
+}
+
+// hline prints the line of an ePOS hline element.
+func (p *Printer) hline(params map[string]string) error {
+	x1, err := atoi(params, "x1")
+	if err != nil {
+		return err
+	}
+	x2, err := atoi(params, "x2")
+	if err != nil {
+		return err
+	}
+	style, err := lineStyle(params)
+	if err != nil {
+		return err
+	}
+	return p.HorizontalLine(x1, x2, style)
+}
+
+// vline returns the position and style of an ePOS vline-begin or vline-end
+// element.
+func vline(params map[string]string) (int, LineStyle, error) {
+	x, err := atoi(params, "x")
+	if err != nil {
+		return 0, 0, err
+	}
+	style, err := lineStyle(params)
+	if err != nil {
+		return 0, 0, err
+	}
+	return x, style, nil
+}
+
+// SoundPattern is a buzzer sound pattern.
+type SoundPattern byte
+
+// Sound patterns. Patterns A to E are the built-in melodies, and patterns 1
+// to 10 the tones of printers with a simple buzzer.
+const (
+	SoundNone     SoundPattern = 0
+	SoundPattern1 SoundPattern = 1
+	SoundPatternA SoundPattern = 'A'
+)
+
+// soundPatterns are the ePOS-Print sound pattern names.
+var soundPatterns = map[string]SoundPattern{
+	"none":       SoundNone,
+	"pattern_a":  SoundPatternA,
+	"pattern_b":  SoundPatternA + 1,
+	"pattern_c":  SoundPatternA + 2,
+	"pattern_d":  SoundPatternA + 3,
+	"pattern_e":  SoundPatternA + 4,
+	"pattern_1":  SoundPattern1,
+	"pattern_2":  SoundPattern1 + 1,
+	"pattern_3":  SoundPattern1 + 2,
+	"pattern_4":  SoundPattern1 + 3,
+	"pattern_5":  SoundPattern1 + 4,
+	"pattern_6":  SoundPattern1 + 5,
+	"pattern_7":  SoundPattern1 + 6,
+	"pattern_8":  SoundPattern1 + 7,
+	"pattern_9":  SoundPattern1 + 8,
+	"pattern_10": SoundPattern1 + 9,
+}
+
+// Sound sounds the buzzer with pattern, repeat times, every cycle
+// milliseconds (ESC ( A function 97). The cycle is rounded down to 100 ms.
+func (p *Printer) Sound(pattern SoundPattern, repeat, cycle int) error {
+	if repeat < 0 || repeat > 255 {
+		return fmt.Errorf("invalid sound repeat: %d", repeat)
+	}
+	if cycle < 100 || cycle > 25500 {
+		return fmt.Errorf("invalid sound cycle: %d", cycle)
+	}
+	return p.write([]byte{0x1b, '(', 'A', 4, 0, 'a', byte(pattern), byte(repeat), byte(cycle / 100)})
+}
+
+// sound sounds the buzzer of an ePOS sound element.
+func (p *Printer) sound(params map[string]string) error {
+	pattern := SoundPatternA
+	if s, ok := params["pattern"]; ok {
+		if pattern, ok = soundPatterns[s]; !ok {
+			return errors.New("unsupported sound pattern: " + s)
+		}
+	}
+
+	repeat, cycle := 1, 1000
+	for name, v := range map[string]*int{"repeat": &repeat, "cycle": &cycle} {
+		if s, ok := params[name]; ok {
+			i, err := strconv.Atoi(s)
+			if err != nil {
+				return err
+			}
+			*v = i
+		}
+	}
+	return p.Sound(pattern, repeat, cycle)
+}
+
+// command sends the raw commands of an ePOS command element, encoded in
+// hexadecimal.
+func (p *Printer) command(data string) error {
+	buf, err := hex.DecodeString(strings.Join(strings.Fields(data), ""))
+	if err != nil {
+		return fmt.Errorf("invalid command: %v", err)
+	}
+	return p.write(buf)
+}
+
+// PaperType is the type of paper loaded in the printer.
+type PaperType byte
+
+// Paper types.
+const (
+	PaperReceipt PaperType = iota
+	PaperReceiptBlackMark
+	PaperLabel
+	PaperLabelBlackMark
+)
+
+// paperTypes are the ePOS-Print paper layout type names.
+var paperTypes = map[string]PaperType{
+	"receipt":    PaperReceipt,
+	"receipt_bm": PaperReceiptBlackMark,
+	"label":      PaperLabel,
+	"label_bm":   PaperLabelBlackMark,
+}
+
+// PaperLayout is the layout of the paper loaded in the printer. Lengths are
+// in units of 0.1 mm, and nil lengths are left out, keeping the printer's
+// current setting.
+type PaperLayout struct {
+	Type PaperType
+
+	// Width and Height are the size of the paper or label.
+	Width, Height *int
+
+	// MarginTop and MarginBottom are the distances from the black mark to
+	// the top and bottom of the print area.
+	MarginTop, MarginBottom *int
+
+	// OffsetCut and OffsetLabel are the distances from the black mark to the
+	// cutting and label peeling positions.
+	OffsetCut, OffsetLabel *int
+}
+
+// SetPaperLayout stores the paper layout in the printer (GS ( E function
+// 49), in user setting mode. The printer resets when leaving the user
+// setting mode.
+func (p *Printer) SetPaperLayout(l PaperLayout) error {
+	if l.Type > PaperLabelBlackMark {
+		return fmt.Errorf("invalid paper type: %d", l.Type)
+	}
+
+	data := []byte{'1', '0' + byte(l.Type)}
+	for _, v := range []*int{l.Width, l.Height, l.MarginTop, l.MarginBottom, l.OffsetCut, l.OffsetLabel} {
+		data = append(data, ';')
+		if v != nil {
+			data = strconv.AppendInt(data, int64(*v), 10)
+		}
+	}
+
+	// enter the user setting mode, set the layout and leave
+	buf := append([]byte{}, userSettingIn...)
+	buf = append(buf, 0x1d, '(', 'E', byte(len(data)), byte(len(data)>>8))
+	buf = append(buf, data...)
+	buf = append(buf, userSettingOut...)
+	return p.write(buf)
+}
+
+// layout sets the paper layout of an ePOS layout element.
+func (p *Printer) layout(params map[string]string) error {
+	var l PaperLayout
+	if s, ok := params["type"]; ok {
+		if l.Type, ok = paperTypes[s]; !ok {
+			return errors.New("invalid paper type: " + s)
+		}
+	}
+	for name, v := range map[string]**int{
+		"width":         &l.Width,
+		"height":        &l.Height,
+		"margin-top":    &l.MarginTop,
+		"margin-bottom": &l.MarginBottom,
+		"offset-cut":    &l.OffsetCut,
+		"offset-label":  &l.OffsetLabel,
+	} {
+		if s, ok := params[name]; ok {
+			i, err := strconv.Atoi(s)
+			if err != nil {
+				return err
+			}
+			*v = &i
+		}
+	}
+	return p.SetPaperLayout(l)
+}
+
+// Recover recovers the printer from a recoverable error, such as a paper
+// jam in the autocutter, and restarts printing from the line where the error
+// occurred (DLE ENQ 1).
+func (p *Printer) Recover() error {
+	return p.write([]byte{0x10, 0x05, 1})
+}
@@ -0,0 +1,106 @@
+package escpos
+
+import (
+	"testing"
+)
+
+func TestWriteNodeElements(t *testing.T) {
+	testCases := []struct {
+		name     string
+		node     string
+		params   map[string]string
+		data     string
+		expected string
+	}{
+		{"Text attributes", "text", map[string]string{"em": "false", "color": "color_2", "linespc": "30"}, "",
+			"\x1bG\x00\x1br\x01\x1b3\x1e"},
+		{"Barcode", "barcode", map[string]string{"type": "ean13"}, "4901234567894",
+			"\x1dH\x00\x1df\x00\x1dkC\x0d4901234567894"},
+		{"Barcode CODE128 code set", "barcode", map[string]string{"type": "code128", "hri": "below", "font": "font_b", "width": "2", "height": "50"}, "{B12",
+			"\x1dh\x32\x1dw\x02\x1dH\x02\x1df\x01\x1dkI\x04{B12"},
+		{"Horizontal line", "hline", map[string]string{"x1": "2", "x2": "9"}, "",
+			"\x1ba\x00\x1dv0\x00\x02\x00\x01\x00\x3f\xc0\x1ba\x00"},
+		{"Double horizontal line", "hline", map[string]string{"x1": "0", "x2": "7", "style": "thin_double"}, "",
+			"\x1ba\x00\x1dv0\x00\x01\x00\x03\x00\xff\x00\xff\x1ba\x00"},
+		{"Vertical line", "vline-begin", map[string]string{"x": "10", "style": "thick"}, "",
+			""},
+		{"Text modes", "text", map[string]string{"em": "true", "ul": "true", "dw": "true"}, "hi",
+			"\x1bG\x01\x1b-\x01\x1d!\x10hi"},
+		{"Text font", "text", map[string]string{"font": "special_a"}, "hi",
+			"\x1bM\x61hi"},
+		{"Text rotate", "text", map[string]string{"rotate": "true"}, "",
+			"\x1bV\x01"},
+		{"Sound", "sound", map[string]string{"pattern": "pattern_2", "repeat": "3", "cycle": "1500"}, "",
+			"\x1b(A\x04\x00a\x02\x03\x0f"},
+		{"Command", "command", nil, "1b 40\n1d56",
+			"\x1b@\x1dV"},
+		{"Layout", "layout", map[string]string{"type": "receipt_bm", "width": "580"}, "",
+			"\x1d(E\x03\x00\x01IN\x1d(E\x0b\x0011;580;;;;;\x1d(E\x04\x00\x02OUT"},
+		{"Layout margins", "layout", map[string]string{"type": "label", "margin-top": "0", "offset-label": "-15"}, "",
+			"\x1d(E\x03\x00\x01IN\x1d(E\x0c\x0012;;;0;;;-15\x1d(E\x04\x00\x02OUT"},
+		{"Recovery", "recovery", nil, "",
+			"\x10\x05\x01"},
+		{"Reset", "reset", nil, "",
+			"\x1b@"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewMockWriter()
+			p, _ := NewPrinter(w)
+			if err := p.WriteNode(tc.node, tc.params, tc.data); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := string(w.GetWritten()); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestWriteNodeErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		node   string
+		params map[string]string
+		data   string
+	}{
+		{"Unknown element", "bogus", nil, ""},
+		{"Area outside page", "area", map[string]string{"x": "0", "y": "0", "width": "10", "height": "10"}, ""},
+		{"Invalid text color", "text", map[string]string{"color": "color_9"}, ""},
+		{"Invalid barcode type", "barcode", map[string]string{"type": "bogus"}, "1234"},
+		{"Invalid barcode data", "barcode", map[string]string{"type": "ean8"}, "12"},
+		{"Invalid line", "hline", map[string]string{"x1": "10", "x2": "2"}, ""},
+		{"Invalid line style", "hline", map[string]string{"x1": "0", "x2": "2", "style": "dotted"}, ""},
+		{"Invalid vertical line", "vline-end", map[string]string{"x": "ten"}, ""},
+		{"Unsupported sound", "sound", map[string]string{"pattern": "error"}, ""},
+		{"Invalid command", "command", nil, "1b4"},
+		{"Invalid text font", "text", map[string]string{"font": "a"}, "hi"},
+		{"Invalid text width", "text", map[string]string{"width": "9"}, ""},
+		{"Invalid paper type", "layout", map[string]string{"type": "roll"}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, _ := NewPrinter(NewMockWriter())
+			if err := p.WriteNode(tc.node, tc.params, tc.data); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestHorizontalLineAlign(t *testing.T) {
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+	p.SetAlign("center")
+	if err := p.HorizontalLine(0, 7, LineThin); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// the line is printed from the left, then the alignment is restored
+	expected := "\x1ba\x01" + "\x1ba\x00\x1dv0\x00\x01\x00\x01\x00\xff" + "\x1ba\x01"
+	if got := string(w.GetWritten()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
@@ -1,43 +1,25 @@
 package escpos
 
 import (
-	"bufio"
+	"context"
 	"encoding/base64"
 	"errors"
-	"flag"
 	"fmt"
 	"image"
-	"image/color"
-	"image/draw"
 	_ "image/gif"
 	_ "image/jpeg"
-	"image/png"
+	_ "image/png"
 	"io"
-	"io/ioutil"
 	"log"
-	"net"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
-	"time"
 
-	"github.com/golang/freetype"
 	"github.com/morezig/goescpos/raster"
-	"github.com/nfnt/resize"
 	"golang.org/x/image/font"
 )
 
-var (
-	dpi        = flag.Float64("dpi", 50, "screen resolution in Dots Per Inch")
-	fontfile   = flag.String("fontfile", "/usr/share/fonts/truetype/dejavu/DejaVuSansMono-Bold.ttf", "filename of the ttf font")
-	hinting    = flag.String("hinting", "none", "none | full")
-	size       = flag.Float64("size", 30, "font size in points")
-	spacing    = flag.Float64("spacing", 1.5, "line spacing (e.g. 2 means double spaced)")
-	wonb       = flag.Bool("whiteonblack", true, "white text on a black background")
-	imageHight = flag.Int("imagehight", 38, "define image hight to be printed")
-)
-
 // Printer wraps sending ESC-POS commands to a io.Writer.
 type Printer struct {
 	// destination
@@ -51,39 +33,88 @@ type Printer struct {
 	emphasize  byte
 	upsidedown byte
 	rotate     byte
+	align      byte
 
 	// state toggles GS[char]
 	reverse, smooth byte
 
+	// character code table state
+	codePage    *CodePage
+	codePages   []*CodePage
+	replacement byte
+
+	// runes mapped to user-defined characters
+	userChars map[rune]byte
+
+	// text rendering, to the printable width of the profile unless the
+	// render options were given
+	renderer    *TextRenderer
+	renderWidth bool
+
+	// default image printing options
+	imageOpts ImageOptions
+
+	// raster banding
+	bandHeight  int
+	flowControl bool
+
+	// extendedStatus enables the ink and peeler status
+	extendedStatus bool
+
+	// profile is the capability profile of the printer, if known
+	profile *Profile
+
+	// monitor reads the printer connection while it runs
+	monitor   *Monitor
+	monitorMu sync.Mutex
+
+	// ctx bounds the printer I/O while Do runs
+	ctx context.Context
+
+	// stale is set when a read was given up, and its reply may come later
+	stale bool
+
+	// err is the first write error encountered, if any
+	err error
+
 	sync.Mutex
 }
 
 // NewPrinter creates a new printer using the specified writer.
-func NewPrinter(w io.ReadWriter /*, opts ...PrinterOption*/) (*Printer, error) {
+func NewPrinter(w io.ReadWriter, opts ...PrinterOption) (*Printer, error) {
 	if w == nil {
 		return nil, errors.New("must supply valid writer")
 	}
 
 	p := &Printer{
-		w:      w,
-		width:  1,
-		height: 1,
+		w:           w,
+		width:       1,
+		height:      1,
+		replacement: defaultReplacement,
+		renderer:    &TextRenderer{opts: DefaultRenderOptions()},
+		bandHeight:  defaultBandHeight,
+	}
+
+	// apply opts
+	for _, o := range opts {
+		if err := o(p); err != nil {
+			return nil, err
+		}
 	}
 
 	return p, nil
 }
 
-func (p *Printer) ReadStatus() bool {
-	statusOnline := []byte{0x10, 0x04, 0x01}
-	p.w.Write(statusOnline)
-	time.Sleep(1 * time.Second)
-	buf := make([]byte, 1)
-	p.w.Read(buf)
-	maskOnline := byte(uint(8))
-	if len(buf) == 0 {
-		return false
-	}
-	return (buf[0] & maskOnline) == 0
+// Err returns the first write error encountered by the printer, if any. Once
+// an error has occurred, all subsequent commands are discarded and return the
+// same error, so that a whole receipt can be checked once at the end.
+func (p *Printer) Err() error {
+	return p.err
+}
+
+// ClearErr clears the sticky write error state of the printer.
+func (p *Printer) ClearErr() {
+	p.err = nil
 }
 
 // Reset resets the printer state.
@@ -95,13 +126,18 @@ func (p *Printer) Reset() {
 	p.emphasize = 0
 	p.upsidedown = 0
 	p.rotate = 0
+	p.align = 0
 
 	p.reverse = 0
 	p.smooth = 0
 }
 
+// CloseConnection closes the printer connection, when it can be closed.
 func (p *Printer) CloseConnection() error {
-	return p.w.(net.Conn).Close()
+	if c, ok := p.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
 }
 
 // Read reads from the printer.
@@ -109,56 +145,94 @@ func (p *Printer) Read(buf []byte) (int, error) {
 	return p.w.Read(buf)
 }
 
-// Write writes buf to printer.
+// Write writes buf to printer. If a previous write failed, nothing is written
+// and the recorded error is returned.
 func (p *Printer) Write(buf []byte) (int, error) {
-	return p.w.Write(buf)
+	if p.err != nil {
+		return 0, p.err
+	}
+	if p.ctx != nil {
+		if err := p.ctx.Err(); err != nil {
+			p.err = timeoutError(p.ctx, "write", err)
+			return 0, p.err
+		}
+	}
+
+	n, err := p.w.Write(buf)
+	if err == nil && n < len(buf) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		err = timeoutError(p.ctx, "write", err)
+		p.err = err
+	}
+
+	return n, err
+}
+
+// write writes buf to the printer, returning only the error.
+func (p *Printer) write(buf []byte) error {
+	_, err := p.Write(buf)
+	return err
 }
 
 // WriteString writes a string to the printer.
 func (p *Printer) WriteString(s string) (int, error) {
-	p.PrintTextImage(s)
-	return p.w.Write([]byte(""))
+	if err := p.PrintTextImage(s); err != nil {
+		return 0, err
+	}
+	return len(s), nil
 }
 
-// Init resets the state of the printer, and writes the initialize code.
-func (p *Printer) Init() {
+// Init resets the state of the printer, and writes the initialize code. The
+// selected code page, if any, is restored after initializing.
+func (p *Printer) Init() error {
 	p.Reset()
-	p.w.Write([]byte("\x1B@"))
+	p.userChars = nil
+	if err := p.write([]byte("\x1B@")); err != nil {
+		return err
+	}
+	if p.codePage != nil {
+		return p.SetCodePage(p.codePage)
+	}
+	return nil
 }
 
 // End terminates the printer session.
-func (p *Printer) End() {
-	p.w.Write([]byte("\xFA"))
-
+func (p *Printer) End() error {
+	return p.write([]byte("\xFA"))
 }
 
 // Cut writes the cut code to the printer.
-func (p *Printer) Cut() {
-	p.w.Write([]byte("\x1DVA0"))
+func (p *Printer) Cut() error {
+	if !p.supports(FeatureCutter) {
+		return nil
+	}
+	return p.write([]byte("\x1DVA0"))
 }
 
 // Cash writes the cash code to the printer.
-func (p *Printer) Cash() {
-	p.w.Write([]byte("\x1B\x70\x00\x0A\xFF"))
+func (p *Printer) Cash() error {
+	return p.write([]byte("\x1B\x70\x00\x0A\xFF"))
 }
 
 // Linefeed writes a line end to the printer.
-func (p *Printer) Linefeed() {
-	p.w.Write([]byte("\n"))
+func (p *Printer) Linefeed() error {
+	return p.write([]byte("\n"))
 }
 
 // FormfeedN writes N formfeeds to the printer.
-func (p *Printer) FormfeedN(n int) {
-	p.w.Write([]byte(fmt.Sprintf("\x1Bd%c", n)))
+func (p *Printer) FormfeedN(n int) error {
+	return p.write([]byte{0x1b, 'd', byte(n)})
 }
 
 // Formfeed writes 1 formfeed to the printer.
-func (p *Printer) Formfeed() {
-	p.FormfeedN(1)
+func (p *Printer) Formfeed() error {
+	return p.FormfeedN(1)
 }
 
 // SetFont sets the font on the printer.
-func (p *Printer) SetFont(font string) {
+func (p *Printer) SetFont(font string) error {
 	f := 0
 
 	switch font {
@@ -173,112 +247,119 @@ func (p *Printer) SetFont(font string) {
 		f = 0
 	}
 
-	p.w.Write([]byte(fmt.Sprintf("\x1BM%c", f)))
-
+	return p.write([]byte{0x1b, 'M', byte(f)})
 }
 
 // SendFontSize sends the font size command to the printer.
-func (p *Printer) SendFontSize() {
-	p.w.Write([]byte(fmt.Sprintf("\x1D!%c", ((p.width-1)<<4)|(p.height-1))))
-
+func (p *Printer) SendFontSize() error {
+	return p.write([]byte{0x1d, '!', ((p.width - 1) << 4) | (p.height - 1)})
 }
 
 // SetFontSize sets the font size state and sends the command to the printer.
-func (p *Printer) SetFontSize(width, height byte) {
+func (p *Printer) SetFontSize(width, height byte) error {
 	if width > 0 && height > 0 && width <= 8 && height <= 8 {
 		p.width, p.height = width, height
-		p.SendFontSize()
-	} else {
-		log.Printf("Invalid font size passed: %d x %d\n", width, height)
+		return p.SendFontSize()
 	}
+
+	return fmt.Errorf("invalid font size: %d x %d", width, height)
 }
 
 // SendUnderline sends the underline command to the printer.
-func (p *Printer) SendUnderline() {
-	p.w.Write([]byte(fmt.Sprintf("\x1B-%c", p.underline)))
+func (p *Printer) SendUnderline() error {
+	return p.write([]byte{0x1b, '-', p.underline})
 }
 
 // SendEmphasize sends the emphasize / doublestrike command to the printer.
-func (p *Printer) SendEmphasize() {
-	p.w.Write([]byte(fmt.Sprintf("\x1BG%c", p.emphasize)))
-
+func (p *Printer) SendEmphasize() error {
+	return p.write([]byte{0x1b, 'G', p.emphasize})
 }
 
 // SendUpsidedown sends the upsidedown command to the printer.
-func (p *Printer) SendUpsidedown() {
-	p.w.Write([]byte(fmt.Sprintf("\x1B{%c", p.upsidedown)))
+func (p *Printer) SendUpsidedown() error {
+	return p.write([]byte{0x1b, '{', p.upsidedown})
 }
 
-// SendRotate sends the rotate command to the printer.
-func (p *Printer) SendRotate() {
-	p.w.Write([]byte(fmt.Sprintf("\x1BR%c", p.rotate)))
+// SendRotate sends the 90° clockwise rotation command to the printer.
+func (p *Printer) SendRotate() error {
+	return p.write([]byte{0x1b, 'V', p.rotate})
 }
 
 // SendReverse sends the reverse command to the printer.
-func (p *Printer) SendReverse() {
-	p.w.Write([]byte(fmt.Sprintf("\x1DB%c", p.reverse)))
+func (p *Printer) SendReverse() error {
+	return p.write([]byte{0x1d, 'B', p.reverse})
 }
 
 // SendSmooth sends the smooth command to the printer.
-func (p *Printer) SendSmooth() {
-	p.w.Write([]byte(fmt.Sprintf("\x1Db%c", p.smooth)))
-
+func (p *Printer) SendSmooth() error {
+	return p.write([]byte{0x1d, 'b', p.smooth})
 }
 
 // SendMoveX sends the move x command to the printer.
-func (p *Printer) SendMoveX(x uint16) {
-	p.Write([]byte{0x1b, 0x24, byte(x % 256), byte(x / 256)})
+func (p *Printer) SendMoveX(x uint16) error {
+	return p.write([]byte{0x1b, 0x24, byte(x % 256), byte(x / 256)})
 }
 
 // SendMoveY sends the move y command to the printer.
-func (p *Printer) SendMoveY(y uint16) {
-	p.Write([]byte{0x1d, 0x24, byte(y % 256), byte(y / 256)})
+func (p *Printer) SendMoveY(y uint16) error {
+	return p.write([]byte{0x1d, 0x24, byte(y % 256), byte(y / 256)})
 }
 
 // SetUnderline sets the underline state and sends it to the printer.
-func (p *Printer) SetUnderline(v byte) {
+func (p *Printer) SetUnderline(v byte) error {
 	p.underline = v
-	p.SendUnderline()
+	return p.SendUnderline()
 }
 
 // SetEmphasize sets the emphasize state and sends it to the printer.
-func (p *Printer) SetEmphasize(u byte) {
+func (p *Printer) SetEmphasize(u byte) error {
 	p.emphasize = u
-	p.SendEmphasize()
+	return p.SendEmphasize()
 }
 
 // SetUpsidedown sets the upsidedown state and sends it to the printer.
-func (p *Printer) SetUpsidedown(v byte) {
+func (p *Printer) SetUpsidedown(v byte) error {
 	p.upsidedown = v
-	p.SendUpsidedown()
+	return p.SendUpsidedown()
 }
 
 // SetRotate sets the rotate state and sends it to the printer.
-func (p *Printer) SetRotate(v byte) {
+func (p *Printer) SetRotate(v byte) error {
 	p.rotate = v
-	p.SendRotate()
+	return p.SendRotate()
 }
 
 // SetReverse sets the reverse state and sends it to the printer.
-func (p *Printer) SetReverse(v byte) {
+func (p *Printer) SetReverse(v byte) error {
 	p.reverse = v
-	p.SendReverse()
+	return p.SendReverse()
 }
 
 // SetSmooth sets the smooth state and sends it to the printer.
-func (p *Printer) SetSmooth(v byte) {
+func (p *Printer) SetSmooth(v byte) error {
 	p.smooth = v
-	p.SendSmooth()
+	return p.SendSmooth()
+}
+
+// SetLineSpacing sets the line spacing to n dots (ESC 3).
+func (p *Printer) SetLineSpacing(n int) error {
+	if n < 0 || n > 255 {
+		return fmt.Errorf("invalid line spacing: %d", n)
+	}
+	return p.write([]byte{0x1b, '3', byte(n)})
 }
 
 // Pulse sends the pulse (open drawer) code to the printer.
-func (p *Printer) Pulse() {
+func (p *Printer) Pulse() error {
+	if !p.supports(FeatureDrawer) {
+		return nil
+	}
 	// with t=2 -- meaning 2*2msec
-	p.w.Write([]byte("\x1Bp\x02"))
+	return p.write([]byte("\x1Bp\x02"))
 }
 
 // SetAlign sets the alignment state and sends it to the printer.
-func (p *Printer) SetAlign(align string) {
+func (p *Printer) SetAlign(align string) error {
 	a := 0
 	switch align {
 	case "left":
@@ -290,12 +371,32 @@ func (p *Printer) SetAlign(align string) {
 	default:
 		log.Printf("Invalid alignment: %s\n", align)
 	}
-	p.w.Write([]byte(fmt.Sprintf("\x1Ba%c", a)))
 
+	return p.sendAlign(byte(a))
+}
+
+// sendAlign sets the alignment state to a and sends it to the printer.
+func (p *Printer) sendAlign(a byte) error {
+	p.align = a
+	return p.write([]byte{0x1b, 'a', a})
+}
+
+// withAlign runs fn with the alignment set to align, then restores the
+// previous alignment, so that an element's align attribute does not carry
+// over to the following elements.
+func (p *Printer) withAlign(align string, fn func() error) error {
+	prev := p.align
+	if err := p.SetAlign(align); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	return p.sendAlign(prev)
 }
 
 // SetLang sets the language state and sends it to the printer.
-func (p *Printer) SetLang(lang string) {
+func (p *Printer) SetLang(lang string) error {
 	l := 0
 
 	switch lang {
@@ -323,105 +424,205 @@ func (p *Printer) SetLang(lang string) {
 		log.Printf("Invalid language: %s\n", lang)
 	}
 
-	p.w.Write([]byte(fmt.Sprintf("\x1BR%c", l)))
+	return p.write([]byte{0x1b, 'R', byte(l)})
+}
+
+// boolAttr returns the boolean attribute name of params as 1 or 0, and
+// whether it is present.
+func boolAttr(params map[string]string, name string) (byte, bool) {
+	v, ok := params[name]
+	if !ok {
+		return 0, false
+	}
+	if v == "true" || v == "1" {
+		return 1, true
+	}
+	return 0, true
+}
+
+// textFont returns the ESC M font number of the ePOS-Print font name.
+func textFont(name string) (byte, bool) {
+	switch name {
+	case "font_a":
+		return 0, true
+	case "font_b":
+		return 1, true
+	case "font_c":
+		return 2, true
+	case "font_d":
+		return 3, true
+	case "font_e":
+		return 4, true
+	case "special_a":
+		return 97, true
+	case "special_b":
+		return 98, true
+	}
+	return 0, false
+}
 
+// styled returns true when a character mode other than the default is set.
+func (p *Printer) styled() bool {
+	return p.width != 1 || p.height != 1 ||
+		p.underline|p.emphasize|p.upsidedown|p.rotate|p.reverse|p.smooth != 0
 }
 
 // Text sends a block of text to the printer using the formatting parameters in params.
 func (p *Printer) Text(params map[string]string, text string) error {
 	// send alignment to printer
 	if align, ok := params["align"]; ok {
-		p.SetAlign(align)
+		if err := p.SetAlign(align); err != nil {
+			return err
+		}
 	}
 
 	// set lang
 	if lang, ok := params["lang"]; ok {
-		p.SetLang(lang)
+		if err := p.SetLang(lang); err != nil {
+			return err
+		}
 	}
 
 	// set smooth
-	if smooth, ok := params["smooth"]; ok && (smooth == "true" || smooth == "1") {
-		p.SetSmooth(1)
+	if v, ok := boolAttr(params, "smooth"); ok {
+		if err := p.SetSmooth(v); err != nil {
+			return err
+		}
 	}
 
 	// set emphasize
-	if em, ok := params["em"]; ok && (em == "true" || em == "1") {
-		p.SetEmphasize(1)
+	if v, ok := boolAttr(params, "em"); ok {
+		if err := p.SetEmphasize(v); err != nil {
+			return err
+		}
 	}
 
 	// set underline
-	if ul, ok := params["ul"]; ok && (ul == "true" || ul == "1") {
-		p.SetUnderline(1)
+	if v, ok := boolAttr(params, "ul"); ok {
+		if err := p.SetUnderline(v); err != nil {
+			return err
+		}
 	}
 
 	// set reverse
-	if reverse, ok := params["reverse"]; ok && (reverse == "true" || reverse == "1") {
-		p.SetReverse(1)
+	if v, ok := boolAttr(params, "reverse"); ok {
+		if err := p.SetReverse(v); err != nil {
+			return err
+		}
 	}
 
 	// set rotate
-	if rotate, ok := params["rotate"]; ok && (rotate == "true" || rotate == "1") {
-		p.SetRotate(1)
+	if v, ok := boolAttr(params, "rotate"); ok {
+		if err := p.SetRotate(v); err != nil {
+			return err
+		}
 	}
 
 	// set font
 	if font, ok := params["font"]; ok {
-		p.SetFont(strings.ToUpper(font[5:6]))
+		n, ok := textFont(font)
+		if !ok {
+			return &ElementError{Name: "text", Reason: "invalid font: " + font}
+		}
+		if err := p.write([]byte{0x1b, 'M', n}); err != nil {
+			return err
+		}
+	}
+
+	// set color
+	if color, ok := params["color"]; ok && color != "none" {
+		c, ok := colors[color]
+		if !ok {
+			return fmt.Errorf("invalid text color: %q", color)
+		}
+		if err := p.SetColor(c); err != nil {
+			return err
+		}
+	}
+
+	// set line spacing
+	if linespc, ok := params["linespc"]; ok {
+		i, err := strconv.Atoi(linespc)
+		if err != nil {
+			return err
+		}
+		if err = p.SetLineSpacing(i); err != nil {
+			return err
+		}
 	}
 
 	// do dw (double font width)
-	if dw, ok := params["dw"]; ok && (dw == "true" || dw == "1") {
-		p.SetFontSize(2, p.height)
+	if v, ok := boolAttr(params, "dw"); ok {
+		if err := p.SetFontSize(1+v, p.height); err != nil {
+			return err
+		}
 	}
 
 	// do dh (double font height)
-	if dh, ok := params["dh"]; ok && (dh == "true" || dh == "1") {
-		p.SetFontSize(p.width, 2)
+	if v, ok := boolAttr(params, "dh"); ok {
+		if err := p.SetFontSize(p.width, 1+v); err != nil {
+			return err
+		}
 	}
 
 	// do font width
 	if width, ok := params["width"]; ok {
-		if i, err := strconv.Atoi(width); err == nil {
-			p.SetFontSize(byte(i), p.height)
-		} else {
-			// log.Println("Invalid font width: %s", width)
+		i, err := strconv.Atoi(width)
+		if err != nil {
+			return err
+		}
+		if err = p.SetFontSize(byte(i), p.height); err != nil {
 			return err
 		}
 	}
 
 	// do font height
 	if height, ok := params["height"]; ok {
-		if i, err := strconv.Atoi(height); err == nil {
-			p.SetFontSize(p.width, byte(i))
-		} else {
-			// log.Fatalf("Invalid font height: %s", height)
+		i, err := strconv.Atoi(height)
+		if err != nil {
+			return err
+		}
+		if err = p.SetFontSize(p.width, byte(i)); err != nil {
 			return err
 		}
 	}
 
-	// do y positioning
+	// do x positioning
 	if x, ok := params["x"]; ok {
-		if i, err := strconv.Atoi(x); err == nil {
-			p.SendMoveX(uint16(i))
-		} else {
-			// log.Fatalf("Invalid x param %s", x)
+		i, err := strconv.Atoi(x)
+		if err != nil {
+			return err
+		}
+		if err = p.SendMoveX(uint16(i)); err != nil {
 			return err
 		}
 	}
 
 	// do y positioning
 	if y, ok := params["y"]; ok {
-		if i, err := strconv.Atoi(y); err == nil {
-			p.SendMoveY(uint16(i))
-		} else {
-			// log.Fatalf("Invalid y param %s", y)
+		i, err := strconv.Atoi(y)
+		if err != nil {
+			return err
+		}
+		if err = p.SendMoveY(uint16(i)); err != nil {
 			return err
 		}
 	}
 
-	// do text replace, then write data
+	// do text replace, then write data, as text when a code page,
+	// user-defined characters or character modes are in use, as the modes
+	// do not apply to text rendered as an image, or as an image otherwise
 	if len(text) > 0 {
-		p.WriteString(textReplacer.Replace(text))
+		write := p.WriteString
+		_, font := params["font"]
+		_, color := params["color"]
+		if p.codePage != nil || len(p.codePages) != 0 || len(p.userChars) != 0 ||
+			p.styled() || font || color {
+			write = p.WriteText
+		}
+		if _, err := write(textReplacer.Replace(text)); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -431,105 +632,66 @@ func (p *Printer) Text(params map[string]string, text string) error {
 func (p *Printer) Feed(params map[string]string) error {
 	// handle lines (form feed X lines)
 	if l, ok := params["line"]; ok {
-		if i, err := strconv.Atoi(l); err == nil {
-			p.FormfeedN(i)
-		} else {
-			// log.Fatalf("Invalid line number %s", l)
+		i, err := strconv.Atoi(l)
+		if err != nil {
+			return err
+		}
+		if err = p.FormfeedN(i); err != nil {
 			return err
 		}
 	}
 
 	// handle units (dots)
 	if u, ok := params["unit"]; ok {
-		if i, err := strconv.Atoi(u); err == nil {
-			p.SendMoveY(uint16(i))
-		} else {
-			// log.Fatalf("Invalid unit number %s", u)
+		i, err := strconv.Atoi(u)
+		if err != nil {
+			return err
+		}
+		if err = p.SendMoveY(uint16(i)); err != nil {
 			return err
 		}
 	}
 
 	// send linefeed
-	p.Linefeed()
+	if err := p.Linefeed(); err != nil {
+		return err
+	}
 
 	// reset variables
 	p.Reset()
 
 	// reset printer
-	p.SendEmphasize()
-	p.SendRotate()
-	p.SendSmooth()
-	p.SendReverse()
-	p.SendUnderline()
-	p.SendUpsidedown()
-	p.SendFontSize()
-	p.SendUnderline()
+	for _, f := range []func() error{
+		p.SendEmphasize,
+		p.SendRotate,
+		p.SendSmooth,
+		p.SendReverse,
+		p.SendUnderline,
+		p.SendUpsidedown,
+		p.SendFontSize,
+	} {
+		if err := f(); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
 // FeedAndCut feeds the printer using the supplied params and then sends a cut
 // command.
-func (p *Printer) FeedAndCut(params map[string]string) {
+func (p *Printer) FeedAndCut(params map[string]string) error {
 	if t, ok := params["type"]; ok && t == "feed" {
-		p.Formfeed()
-	}
-
-	p.Cut()
-}
-
-// Barcode sends a barcode to the printer.
-func (p *Printer) Barcode(barcode string, format int) {
-	code := ""
-	switch format {
-	case 0:
-		code = "\x00"
-	case 1:
-		code = "\x01"
-	case 2:
-		code = "\x02"
-	case 3:
-		code = "\x03"
-	case 4:
-		code = "\x04"
-	case 73:
-		code = "\x49"
-	}
-
-	// reset settings
-	p.Reset()
-
-	// set align
-	p.SetAlign("center")
-
-	// write barcode
-	if format > 69 {
-		p.w.Write([]byte(fmt.Sprintf("\x1dk"+code+"%v%v", len(barcode), barcode)))
-
-	} else if format < 69 {
-		p.w.Write([]byte(fmt.Sprintf("\x1dk"+code+"%v\x00", barcode)))
+		if err := p.Formfeed(); err != nil {
+			return err
+		}
 	}
-	p.w.Write([]byte(barcode))
-
-}
-
-// gSendsend graphics headers.
-func (p *Printer) gSend(m byte, fn byte, data []byte) {
-	l := len(data) + 2
-
-	p.w.Write([]byte("\x1b(L"))
-	p.Write([]byte{byte(l % 256), byte(l / 256), m, fn})
-	p.w.Write([]byte(data))
 
+	return p.Cut()
 }
 
 // Image writes an image using the supplied params.
 func (p *Printer) Image(params map[string]string, data string) error {
-	// send alignment to printer
-	if align, ok := params["align"]; ok {
-		p.SetAlign(align)
-	}
-
 	// get width
 	wstr, ok := params["width"]
 	if !ok {
@@ -565,31 +727,29 @@ func (p *Printer) Image(params map[string]string, data string) error {
 
 	log.Printf("Image len:%d w: %d h: %d\n", len(dec), width, height)
 
-	// $imgHeader = self::dataHeader(array($img -> getWidth(), $img -> getHeight()), true);
-	// $tone = '0';
-	// $colors = '1';
-	// $xm = (($size & self::IMG_DOUBLE_WIDTH) == self::IMG_DOUBLE_WIDTH) ? chr(2) : chr(1);
-	// $ym = (($size & self::IMG_DOUBLE_HEIGHT) == self::IMG_DOUBLE_HEIGHT) ? chr(2) : chr(1);
-	//
-	// $header = $tone . $xm . $ym . $colors . $imgHeader;
-	// $this -> graphicsSendData('0', 'p', $header . $img -> toRasterFormat());
-	// $this -> graphicsSendData('0', '2');
-
-	header := []byte{
-		byte('0'), 0x01, 0x01, byte('1'),
+	// colour of the image, from the ePOS color attribute
+	color := ColorFirst
+	if c, ok := colors[params["color"]]; ok {
+		color = c
 	}
 
-	a := append(header, dec...)
-
-	p.gSend(byte('0'), byte('p'), a)
-	p.gSend(byte('0'), byte('2'), []byte{})
+	lineWidth := (width + 7) / 8
+	if len(dec) < lineWidth*height {
+		return errors.New("image data too short")
+	}
 
-	return nil
+	print := func() error {
+		return p.graphicsPlanes(toneMonochrome, color, width, height, lineWidth, dec)
+	}
+	if align, ok := params["align"]; ok {
+		return p.withAlign(align, print)
+	}
+	return print()
 }
 
 // WriteNode writes a node of type name with the supplied params and data to
 // the printer.
-func (p *Printer) WriteNode(name string, params map[string]string, data string) {
+func (p *Printer) WriteNode(name string, params map[string]string, data string) error {
 	cstr := ""
 	if data != "" {
 		str := data
@@ -602,20 +762,95 @@ func (p *Printer) WriteNode(name string, params map[string]string, data string)
 
 	switch name {
 	case "text":
-		p.Text(params, data)
+		return p.Text(params, data)
 
 	case "feed":
-		p.Feed(params)
+		return p.Feed(params)
 
 	case "cut":
-		p.FeedAndCut(params)
+		return p.FeedAndCut(params)
 
 	case "pulse":
-		p.Pulse()
+		return p.Pulse()
 
 	case "image":
-		p.Image(params, data)
+		return p.Image(params, data)
+
+	case "symbol":
+		return p.writeSymbol(params, data)
+
+	case "logo":
+		return p.logo(params)
+
+	case "barcode":
+		return p.writeBarcode(params, data)
+
+	case "hline":
+		return p.hline(params)
+
+	case "vline-begin", "vline-end":
+		// vertical lines run alongside the text printed between the two
+		// elements, which printers cannot do in standard mode, so like ePOS
+		// printers without the feature, they are skipped
+		_, _, err := vline(params)
+		return err
+
+	case "sound":
+		return p.sound(params)
+
+	case "command":
+		return p.command(data)
+
+	case "layout":
+		return p.layout(params)
+
+	case "recovery":
+		return p.Recover()
+
+	case "reset":
+		return p.Init()
+
+	case "page":
+		return &ElementError{Name: name, Reason: "must be written with WriteNodes"}
+
+	case "area", "direction", "position":
+		return &ElementError{Name: name, Reason: "outside of page"}
+	}
+
+	return &ElementError{Name: name, Reason: "unsupported element"}
+}
+
+// ElementError is the error returned when an ePOS element is unknown or
+// misplaced.
+type ElementError struct {
+	Name   string
+	Reason string
+}
+
+// Error satisfies the error interface.
+func (e *ElementError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Name, e.Reason)
+}
+
+// WriteNodes writes the nodes to the printer, stopping at the first error.
+// Page elements are written with their children in page mode, and the
+// children of epos-print elements are written in their place.
+func (p *Printer) WriteNodes(nodes []Node) error {
+	for _, n := range nodes {
+		var err error
+		switch n.Name() {
+		case "epos-print":
+			err = p.WriteNodes(n.Nodes)
+		case "page":
+			err = p.writePage(n.Nodes)
+		default:
+			err = p.WriteNode(n.Name(), n.Attributes(), n.Content)
+		}
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // textReplacer is a simple text replacer for the only valid XML encoded
@@ -639,8 +874,71 @@ var textReplacer = strings.NewReplacer(
 	"&amp;", "&",
 )
 
+// ImageOptions are the options used to print an image.
+type ImageOptions struct {
+	// Type is the printing type, raster.BitImage (GS v 0) by default.
+	Type raster.PrintingType
+
+	// Align is the alignment of the image, "left", "center" or "right".
+	Align string
+
+	// Converter converts the image to a raster. A zero MaxWidth or
+	// Threshold is replaced with the default.
+	raster.Converter
+}
+
+// withDefaults returns a copy of the options, with the defaults applied to
+// unset fields.
+func (o *ImageOptions) withDefaults() ImageOptions {
+	var opts ImageOptions
+	if o != nil {
+		opts = *o
+	}
+	if opts.Align == "" {
+		opts.Align = "center"
+	}
+	if opts.Threshold == 0 {
+		opts.Threshold = 0.5
+	}
+	if opts.MaxWidth == 0 {
+		opts.MaxWidth = defaultMaxWidth
+	}
+	return opts
+}
+
+// imageOptions returns the options used to print an image with opts, the
+// printer's image options when nil, with the defaults applied. An unset
+// MaxWidth is the printable width of the printer's profile, if any.
+func (p *Printer) imageOptions(opts *ImageOptions) ImageOptions {
+	if opts == nil {
+		opts = &p.imageOpts
+	}
+	o := *opts
+	if o.MaxWidth == 0 && p.profile != nil {
+		o.MaxWidth = p.profile.Width
+	}
+	return o.withDefaults()
+}
+
+// SetImageOptions sets the options used to print images when none are given,
+// including by PrintImage and PrintTextImage.
+func (p *Printer) SetImageOptions(opts ImageOptions) {
+	p.imageOpts = opts
+}
+
+// PrintImageData prints img, converted to a raster with opts. A nil opts
+// uses the image options of the printer.
+func (p *Printer) PrintImageData(img image.Image, opts *ImageOptions) error {
+	o := p.imageOptions(opts)
+
+	if err := p.SetAlign(o.Align); err != nil {
+		return err
+	}
+	return o.Converter.Print(img, p, o.Type)
+}
+
 // PrintImage Print Image
-func (p *Printer) PrintImage(imgPath string, printImageType string) error {
+func (p *Printer) PrintImage(imgPath string, printImageType raster.PrintingType) error {
 	imgFile, err := os.Open(imgPath)
 	if err != nil {
 		// log.Fatal(err)
@@ -648,10 +946,6 @@ func (p *Printer) PrintImage(imgPath string, printImageType string) error {
 	}
 
 	img, imgFormat, err := image.Decode(imgFile)
-	imgConfig, _, _ := image.DecodeConfig(imgFile)
-	if imgConfig.Height > 450 || imgConfig.Width > 450 {
-		img = resize.Resize(450, 0, img, resize.Lanczos3)
-	}
 	imgFile.Close()
 	if err != nil {
 		// log.Fatal(err)
@@ -659,176 +953,89 @@ func (p *Printer) PrintImage(imgPath string, printImageType string) error {
 	}
 	log.Print("Loaded image, format: ", imgFormat)
 
-	rasterConv := &raster.Converter{
-		MaxWidth:  512,
-		Threshold: 0.5,
-	}
-	p.SetAlign("center")
-	rasterConv.Print(img, p, printImageType)
-	return nil
+	opts := p.imageOpts
+	opts.Type = printImageType
+	return p.PrintImageData(img, &opts)
 }
 
 // SetWhiteOnBlack sets the background for the image to white for true or black for false
 func (p *Printer) SetWhiteOnBlack(wonbVal bool) {
-	*wonb = wonbVal
+	p.renderer.opts.WhiteOnBlack = wonbVal
 }
 
 // SetFontSizePoint sets font size in points for some selected font
 func (p *Printer) SetFontSizePoints(fontSize float64) {
-	*size = fontSize
+	p.renderer.opts.FontSize = fontSize
 }
 
 // SetDPI sets resolution in dots per inch for the image
 func (p *Printer) SetDPI(resolution float64) {
-	*dpi = resolution
+	p.renderer.opts.DPI = resolution
 }
 
 // SetFontFile to choose a certien font to print the image with
 func (p *Printer) SetFontFile(filepath string) {
-	*fontfile = filepath
+	p.renderer.opts.FontFile = filepath
 }
 
 // SetHinting sets hinting
 func (p *Printer) SetHinting(hintingVal string) {
-	*hinting = hintingVal
+	switch hintingVal {
+	default:
+		p.renderer.opts.Hinting = font.HintingNone
+	case "full":
+		p.renderer.opts.Hinting = font.HintingFull
+	}
 }
 
 // SetSpacing set spacing between lines in image
 func (p *Printer) SetSpacing(spacingVal float64) {
-	*spacing = spacingVal
+	p.renderer.opts.Spacing = spacingVal
 }
 
 func (p *Printer) SetImageHight(hight int) {
-	*imageHight = hight
+	p.renderer.opts.ImageHeight = hight
 }
 
-// PrintTextImage takes a string convert it to an image and print it
-func (p *Printer) PrintTextImage(text string) error {
-	// flag.Parse()
-	// Read the font data.
-	fontBytes, err := ioutil.ReadFile(*fontfile)
-	if err != nil {
-		return err
-	}
-	f, err := freetype.ParseFont(fontBytes)
-	if err != nil {
-		return err
-	}
-
-	// Initialize the context.
-	fg, bg := image.Black, image.White
-	ruler := color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
-	if *wonb {
-		fg, bg = image.White, image.Black
-		ruler = color.RGBA{0x22, 0x22, 0x22, 0xff}
-	}
-	rgba := image.NewRGBA(image.Rect(0, 0, 760, *imageHight))
-	draw.Draw(rgba, rgba.Bounds(), bg, image.ZP, draw.Src)
-	c := freetype.NewContext()
-	c.SetDPI(*dpi)
-	c.SetFont(f)
-	c.SetFontSize(*size)
-	c.SetClip(rgba.Bounds())
-	c.SetDst(rgba)
-	c.SetSrc(fg)
-	switch *hinting {
-	default:
-		c.SetHinting(font.HintingNone)
-	case "full":
-		c.SetHinting(font.HintingFull)
-	}
-
-	// Draw the guidelines.
-	for i := 0; i < 200; i++ {
-		rgba.Set(10, 10+i, ruler)
-		rgba.Set(10+i, 10, ruler)
-	}
-
-	// Draw the text.
-	pt := freetype.Pt(10, 10+int(c.PointToFixed(*size)>>6))
-	_, err = c.DrawString(text, pt)
-	if err != nil {
-		return err
-	}
-	pt.Y += c.PointToFixed(*size * *spacing)
+// SetTextAlign sets the alignment of text printed as an image
+func (p *Printer) SetTextAlign(align TextAlign) {
+	p.renderer.opts.Align = align
+}
 
-	// Save that RGBA image to disk.
-	outFile, err := os.Create("/var/tmp/posTextImage.png")
-	if err != nil {
-		// os.Exit(1)
-		return err
-	}
+// TextRenderer returns the renderer used by the printer to print text as an
+// image.
+func (p *Printer) TextRenderer() *TextRenderer {
+	return p.renderer
+}
 
-	defer outFile.Close()
-	b := bufio.NewWriter(outFile)
-	err = png.Encode(b, rgba)
-	if err != nil {
-		// os.Exit(1)
-		return err
-	}
-	err = b.Flush()
+// PrintTextImage takes a string convert it to an image and print it
+func (p *Printer) PrintTextImage(text string) error {
+	img, err := p.renderer.Render(text)
 	if err != nil {
-		// os.Exit(1)
 		return err
 	}
-
-	p.PrintImage(outFile.Name(), "bitImage")
-
-	return nil
+	return p.PrintImageData(img, nil)
 }
 
 // TextToRaster takes a string, font size, boolean value if true will print text black background white
 // if false will print text white background black
 // return slice bytes of raster image with width and height
 func (p *Printer) TextToRaster(text string, fontSize float64, wb bool) (data []byte, width int, height int, err error) {
-	fontBytes, err := ioutil.ReadFile(*fontfile)
-	if err != nil {
-		return nil, 0, 0, err
-	}
-	f, err := freetype.ParseFont(fontBytes)
+	opts := p.renderer.Options()
+	opts.FontSize = fontSize
+	opts.WhiteOnBlack = wb
+	r, err := NewTextRenderer(opts)
 	if err != nil {
 		return nil, 0, 0, err
 	}
 
-	// Initialize the context.
-	fg, bg := image.Black, image.White
-	ruler := color.RGBA{0xdd, 0xdd, 0xdd, 0xff}
-	if wb {
-		fg, bg = image.White, image.Black
-		ruler = color.RGBA{0x22, 0x22, 0x22, 0xff}
-	}
-	rgba := image.NewRGBA(image.Rect(0, 0, 760, *imageHight))
-	draw.Draw(rgba, rgba.Bounds(), bg, image.ZP, draw.Src)
-	c := freetype.NewContext()
-	c.SetDPI(*dpi)
-	c.SetFont(f)
-	c.SetFontSize(fontSize)
-	c.SetClip(rgba.Bounds())
-	c.SetDst(rgba)
-	c.SetSrc(fg)
-	switch *hinting {
-	default:
-		c.SetHinting(font.HintingNone)
-	case "full":
-		c.SetHinting(font.HintingFull)
-	}
-
-	// Draw the guidelines.
-	for i := 0; i < 200; i++ {
-		rgba.Set(10, 10+i, ruler)
-		rgba.Set(10+i, 10, ruler)
-	}
-
-	// Draw the text.
-	pt := freetype.Pt(10, 10+int(c.PointToFixed(fontSize)>>6))
-	_, err = c.DrawString(text, pt)
+	rgba, err := r.Render(text)
 	if err != nil {
 		return nil, 0, 0, err
 	}
-	pt.Y += c.PointToFixed(fontSize * *spacing)
 
 	rasterConv := &raster.Converter{
-		MaxWidth:  512,
+		MaxWidth:  defaultMaxWidth,
 		Threshold: 0.5,
 	}
 
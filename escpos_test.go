@@ -0,0 +1,164 @@
+package escpos
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/morezig/goescpos/raster"
+)
+
+// failingWriter is an io.ReadWriter that fails every write after the first
+// n bytes have been written.
+type failingWriter struct {
+	MockWriter
+	n     int
+	err   error
+	calls int
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	f.calls++
+	if len(f.written)+len(p) > f.n {
+		return 0, f.err
+	}
+	return f.MockWriter.Write(p)
+}
+
+func TestPrinterStickyError(t *testing.T) {
+	errOffline := errors.New("printer offline")
+	w := &failingWriter{MockWriter: *NewMockWriter(), n: 2, err: errOffline}
+
+	p, err := NewPrinter(w)
+	if err != nil {
+		t.Fatalf("Failed to create printer: %v", err)
+	}
+
+	if err := p.Init(); err != nil {
+		t.Fatalf("Init should succeed, got %v", err)
+	}
+	if err := p.Cut(); err != errOffline {
+		t.Errorf("Expected Cut to return %v, got %v", errOffline, err)
+	}
+
+	calls := w.calls
+	if err := p.SetAlign("center"); err != errOffline {
+		t.Errorf("Expected SetAlign to return sticky error, got %v", err)
+	}
+	if err := p.Feed(map[string]string{"line": "2"}); err != errOffline {
+		t.Errorf("Expected Feed to return sticky error, got %v", err)
+	}
+	if w.calls != calls {
+		t.Errorf("Expected no writes after failure, got %d", w.calls-calls)
+	}
+	if p.Err() != errOffline {
+		t.Errorf("Expected Err to return %v, got %v", errOffline, p.Err())
+	}
+
+	p.ClearErr()
+	if p.Err() != nil {
+		t.Errorf("Expected Err to be nil after ClearErr, got %v", p.Err())
+	}
+}
+
+func TestPrinterCommands(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cmd      func(p *Printer) error
+		expected string
+	}{
+		{"Init", (*Printer).Init, "\x1B@"},
+		{"Cut", (*Printer).Cut, "\x1DVA0"},
+		{"FormfeedN", func(p *Printer) error { return p.FormfeedN(200) }, "\x1Bd\xc8"},
+		{"SetFont", func(p *Printer) error { return p.SetFont("B") }, "\x1BM\x01"},
+		{"SetAlign", func(p *Printer) error { return p.SetAlign("right") }, "\x1Ba\x02"},
+		{"SetFontSize", func(p *Printer) error { return p.SetFontSize(2, 3) }, "\x1D!\x12"},
+		{"Pulse", (*Printer).Pulse, "\x1Bp\x02"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewMockWriter()
+			p, _ := NewPrinter(w)
+			if err := tc.cmd(p); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := string(w.GetWritten()); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSetFontSizeInvalid(t *testing.T) {
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+	for _, size := range [][2]byte{{0, 1}, {1, 9}} {
+		if err := p.SetFontSize(size[0], size[1]); err == nil {
+			t.Errorf("Expected error for font size %d x %d", size[0], size[1])
+		}
+	}
+	if len(w.GetWritten()) != 0 || p.width != 1 || p.height != 1 {
+		t.Errorf("Expected printer unchanged, got %q", w.GetWritten())
+	}
+}
+
+func TestServerReportsWriteError(t *testing.T) {
+	w := &failingWriter{MockWriter: *NewMockWriter(), n: 0, err: errors.New("broken pipe")}
+	server, err := NewServer(w)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	soapBody := `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body xmlns:m="http://www.epson-pos.com/schemas/2011/03/epos-print">
+    <cut type="feed"/>
+  </s:Body>
+</s:Envelope>`
+
+	req := httptest.NewRequest("POST", DefaultEndpoint, strings.NewReader(soapBody))
+	res := httptest.NewRecorder()
+	server.ServeHTTP(res, req)
+
+	body := res.Body.String()
+	if !strings.Contains(body, `success="false"`) {
+		t.Errorf("Response should indicate failure, got %s", body)
+	}
+	if !strings.Contains(body, `code="EX_BADPORT"`) {
+		t.Errorf("Response should contain EX_BADPORT code, got %s", body)
+	}
+}
+
+func TestPrintImageData(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 10, 2))
+	for x := 0; x < 10; x++ {
+		img.SetGray(x, 0, color.Gray{0xff})
+	}
+
+	testCases := []struct {
+		name     string
+		opts     *ImageOptions
+		expected string
+	}{
+		{"Defaults", nil, "\x1ba\x01\x1dv0\x00\x02\x00\x02\x00\xff\xc0\x00\x00"},
+		{"Left", &ImageOptions{Align: "left"}, "\x1ba\x00\x1dv0\x00\x02\x00\x02\x00\xff\xc0\x00\x00"},
+		{"Max width", &ImageOptions{Converter: raster.Converter{MaxWidth: 8, Fit: raster.FitNone}}, "\x1ba\x01\x1dv0\x00\x01\x00\x02\x00\xff\x00"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewMockWriter()
+			p, _ := NewPrinter(w)
+			if err := p.PrintImageData(img, tc.opts); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := string(w.GetWritten()); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -10,8 +10,8 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 
-	"github.com/cloudinn/escpos"
-	"github.com/cloudinn/escpos/raster"
+	"github.com/morezig/goescpos"
+	"github.com/morezig/goescpos/raster"
 )
 
 var (
@@ -21,11 +21,26 @@ var (
 	align     = flag.String("a", "center", "Alignment (left, center, right)")
 	doCut     = flag.Bool("c", false, "Cut after print")
 	maxWidth  = flag.Int("printer-max-width", 512, "Printer max width in pixels")
+	dither    = flag.String("d", "none", "Dithering (none, floyd-steinberg, atkinson, stucki, bayer4, bayer8)")
+	otsu      = flag.Bool("otsu", false, "Compute the black/white threshold from the image")
+	gamma     = flag.Float64("gamma", 1, "Gamma correction")
+	contrast  = flag.Float64("contrast", 0, "Contrast adjustment (-1 to 1)")
+	bright    = flag.Float64("brightness", 0, "Brightness adjustment (-1 to 1)")
+	printType = flag.String("type", "bitImage", "Printing type (bitImage, graphics, column8Single, column8Double, column24Single, column24Double)")
 )
 
 func main() {
 	flag.Parse()
 
+	d, err := raster.ParseDither(*dither)
+	if err != nil {
+		log.Fatal(err)
+	}
+	typ, err := raster.ParsePrintingType(*printType)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	imgFile, err := os.Open(*imgPath)
 	if err != nil {
 		log.Fatal(err)
@@ -53,15 +68,23 @@ func main() {
 
 	ep.Init()
 
-	ep.SetAlign(*align)
-
-	rasterConv := &raster.Converter{
-		MaxWidth:  *maxWidth,
-		Threshold: *threshold,
+	err = ep.PrintImageData(img, &escpos.ImageOptions{
+		Type:  typ,
+		Align: *align,
+		Converter: raster.Converter{
+			MaxWidth:      *maxWidth,
+			Threshold:     *threshold,
+			AutoThreshold: *otsu,
+			Dither:        d,
+			Gamma:         *gamma,
+			Contrast:      *contrast,
+			Brightness:    *bright,
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	rasterConv.Print(img, ep)
-
 	if *doCut {
 		ep.Cut()
 	}
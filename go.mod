@@ -6,4 +6,6 @@ require (
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
 	golang.org/x/image v0.0.0-20190729225735-1bd0cf576493
+	golang.org/x/text v0.16.0
+	rsc.io/qr v0.2.0
 )
@@ -0,0 +1,284 @@
+package escpos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidInfo is returned when the printer answers an identification or
+// setting request with data that is not valid.
+var ErrInvalidInfo = errors.New("invalid printer information")
+
+// Identification requests, as used by GS I.
+const (
+	infoModelID      = 1
+	infoTypeID       = 2
+	infoVersionID    = 3
+	infoFirmware     = 'A'
+	infoManufacturer = 'B'
+	infoModel        = 'C'
+	infoSerialNumber = 'D'
+)
+
+// Customized setting numbers, as used by GS ( E function 6.
+const (
+	settingPaperWidth = 3
+	settingDensity    = 5
+	settingSpeed      = 6
+)
+
+// paperWidths are the paper widths in mm, by paper width setting value.
+var paperWidths = map[int]int{
+	1: 40,
+	2: 58,
+	3: 60,
+	4: 70,
+	5: 76,
+	6: 80,
+}
+
+var (
+	// userSettingIn enters the user setting mode (GS ( E function 1).
+	userSettingIn = []byte{0x1d, '(', 'E', 3, 0, 1, 'I', 'N'}
+
+	// userSettingOut leaves the user setting mode and resets the printer
+	// (GS ( E function 2).
+	userSettingOut = []byte{0x1d, '(', 'E', 4, 0, 2, 'O', 'U', 'T'}
+)
+
+// printableWidths are the printable widths in mm, by paper width in mm.
+var printableWidths = map[int]int{
+	40: 32,
+	58: 48,
+	60: 52,
+	70: 60,
+	76: 68,
+	80: 72,
+}
+
+// maxInfoLength is the longest block of information read from the printer.
+const maxInfoLength = 80
+
+// infoQueryTimeout is how long the printer is given to answer each
+// identification and setting request.
+const infoQueryTimeout = 500 * time.Millisecond
+
+// userSettingTimeout is how long leaving the user setting mode may take once
+// the context of the request is done.
+const userSettingTimeout = time.Second
+
+// PrinterInfo is the identification and settings of a printer.
+type PrinterInfo struct {
+	// ModelID, TypeID and VersionID are the printer's identification bytes.
+	ModelID, TypeID, VersionID byte
+
+	// MultiByte is true when the printer supports multi-byte characters.
+	MultiByte bool
+
+	// Cutter is true when an autocutter is installed.
+	Cutter bool
+
+	// Firmware, Manufacturer, Model and SerialNumber identify the printer,
+	// on printers supporting them.
+	Firmware, Manufacturer, Model, SerialNumber string
+
+	// PaperWidth is the width of the paper in mm, or 0 when unknown.
+	PaperWidth int
+
+	// PrintDensity is the print density, from -6 (lightest) to 6, 0 being
+	// the standard density.
+	PrintDensity int
+
+	// PrintSpeed is the print speed level, higher being faster.
+	PrintSpeed int
+}
+
+// PrintableWidth returns the printable width in dots of the paper, for a
+// printer printing dpi dots per inch, such as the DPI of its profile, or 0
+// when the paper width or resolution is unknown.
+// The width is rounded to whole bytes: 80 mm paper is 576 dots wide at 203
+// dpi, and 512 at 180 dpi.
+func (i PrinterInfo) PrintableWidth(dpi int) int {
+	mm, ok := printableWidths[i.PaperWidth]
+	if !ok {
+		return 0
+	}
+	return int(math.Round(float64(mm)*float64(dpi)/25.4/8)) * 8
+}
+
+// Info identifies the printer (GS I) and reads its paper width, print
+// density and print speed (GS ( E function 6). The settings are read in user
+// setting mode, which resets the printer when left, so Info should be called
+// before printing. Printers not supporting one of the requests do not answer
+// it, and the information it asks for is left empty after half a second,
+// so that the information of the other requests is returned. Info gives up
+// when ctx is done. The printer's writer must not be buffered.
+func (p *Printer) Info(ctx context.Context) (PrinterInfo, error) {
+	var info PrinterInfo
+
+	for _, q := range []struct {
+		n byte
+		v *byte
+	}{
+		{infoModelID, &info.ModelID},
+		{infoTypeID, &info.TypeID},
+		{infoVersionID, &info.VersionID},
+	} {
+		v := q.v
+		err := p.query(ctx, []byte{0x1d, 'I', q.n}, func(ctx context.Context) error {
+			b := make([]byte, 1)
+			if err := p.readFull(ctx, b); err != nil {
+				return err
+			}
+			*v = b[0]
+			return nil
+		})
+		if err != nil {
+			return info, err
+		}
+	}
+	info.MultiByte = info.TypeID&0x01 != 0
+	info.Cutter = info.TypeID&0x02 != 0
+
+	for _, q := range []struct {
+		n byte
+		v *string
+	}{
+		{infoFirmware, &info.Firmware},
+		{infoManufacturer, &info.Manufacturer},
+		{infoModel, &info.Model},
+		{infoSerialNumber, &info.SerialNumber},
+	} {
+		v := q.v
+		err := p.query(ctx, []byte{0x1d, 'I', q.n}, func(ctx context.Context) error {
+			data, err := p.readBlock(ctx, 0x5f)
+			if err != nil {
+				return err
+			}
+			*v = string(data)
+			return nil
+		})
+		if err != nil {
+			return info, err
+		}
+	}
+
+	if err := p.readSettings(ctx, &info); err != nil {
+		return info, err
+	}
+
+	// paper widths are numbered, and densities below 0 wrap around
+	info.PaperWidth = paperWidths[info.PaperWidth]
+	if info.PrintDensity > 0xff00 {
+		info.PrintDensity -= 0x10000
+	}
+
+	return info, nil
+}
+
+// readSettings reads the paper width, print density and print speed in user
+// setting mode, always leaving the mode once entered, as the printer does not
+// print in it.
+func (p *Printer) readSettings(ctx context.Context, info *PrinterInfo) (err error) {
+	if err := p.writeContext(ctx, userSettingIn); err != nil {
+		return err
+	}
+	defer func() {
+		// leave the mode even when ctx is done
+		octx, cancel := context.WithTimeout(context.Background(), userSettingTimeout)
+		defer cancel()
+		if oerr := p.writeContext(octx, userSettingOut); err == nil {
+			err = oerr
+		}
+	}()
+
+	for _, q := range []struct {
+		a byte
+		v *int
+	}{
+		{settingPaperWidth, &info.PaperWidth},
+		{settingDensity, &info.PrintDensity},
+		{settingSpeed, &info.PrintSpeed},
+	} {
+		v := q.v
+		err := p.query(ctx, []byte{0x1d, '(', 'E', 2, 0, 6, q.a}, func(ctx context.Context) error {
+			var err error
+			*v, err = p.readSetting(ctx)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// query sends the request req and reads its answer with read, giving the
+// printer infoQueryTimeout to answer. Requests the printer does not answer in
+// time are skipped, unless ctx is done. On connections without read
+// deadlines, the answer is waited for as long as ctx allows.
+func (p *Printer) query(ctx context.Context, req []byte, read func(ctx context.Context) error) error {
+	if err := p.request(ctx, req); err != nil {
+		return err
+	}
+
+	qctx, cancel := context.WithTimeout(ctx, infoQueryTimeout)
+	defer cancel()
+	err := read(qctx)
+	if err == ErrNoReadDeadline {
+		err = read(ctx)
+	}
+	if _, ok := err.(*TimeoutError); ok && ctx.Err() == nil {
+		return nil
+	}
+	return err
+}
+
+// readBlock reads a block of data from the printer, starting with header and
+// ending with NUL, and returns the data between them.
+func (p *Printer) readBlock(ctx context.Context, header ...byte) ([]byte, error) {
+	h := make([]byte, len(header))
+	if err := p.readFull(ctx, h); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(h, header) {
+		return nil, ErrInvalidInfo
+	}
+
+	var data []byte
+	b := make([]byte, 1)
+	for {
+		if err := p.readFull(ctx, b); err != nil {
+			return nil, err
+		}
+		if b[0] == 0 {
+			return data, nil
+		}
+		if len(data) == maxInfoLength {
+			return nil, ErrInvalidInfo
+		}
+		data = append(data, b[0])
+	}
+}
+
+// readSetting reads a customized setting value sent by the printer, as the
+// setting number and the value in decimal, separated by 0x1f.
+func (p *Printer) readSetting(ctx context.Context) (int, error) {
+	data, err := p.readBlock(ctx, 0x37, 0x27)
+	if err != nil {
+		return 0, err
+	}
+	i := bytes.IndexByte(data, 0x1f)
+	if i < 0 {
+		return 0, ErrInvalidInfo
+	}
+	v, err := strconv.Atoi(string(data[i+1:]))
+	if err != nil {
+		return 0, ErrInvalidInfo
+	}
+	return v, nil
+}
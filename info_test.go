@@ -0,0 +1,146 @@
+package escpos
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestInfo(t *testing.T) {
+	w := NewMockWriter()
+	w.buffer.WriteString("\x20\x02\x05" +
+		"_1.00 ESC/POS\x00_EPSON\x00_TM-T88V\x00_X8H1234567\x00" +
+		"7'\x03\x1f6\x00" + "7'\x05\x1f65534\x00" + "7'\x06\x1f9\x00")
+	p, _ := NewPrinter(w)
+
+	info, err := p.Info(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := PrinterInfo{
+		ModelID: 0x20, TypeID: 0x02, VersionID: 0x05,
+		Cutter:       true,
+		Firmware:     "1.00 ESC/POS",
+		Manufacturer: "EPSON",
+		Model:        "TM-T88V",
+		SerialNumber: "X8H1234567",
+		PaperWidth:   80,
+		PrintDensity: -2,
+		PrintSpeed:   9,
+	}
+	if info != expected {
+		t.Errorf("Expected %+v, got %+v", expected, info)
+	}
+	for _, c := range []struct{ paper, dpi, width int }{
+		{80, 203, 576},
+		{80, 180, 512},
+		{58, 203, 384},
+		{76, 203, 544},
+		{0, 203, 0},
+	} {
+		info.PaperWidth = c.paper
+		if w := info.PrintableWidth(c.dpi); w != c.width {
+			t.Errorf("Expected printable width %d for %d mm at %d dpi, got %d", c.width, c.paper, c.dpi, w)
+		}
+	}
+
+	written := "\x1dI\x01\x1dI\x02\x1dI\x03\x1dIA\x1dIB\x1dIC\x1dID" +
+		"\x1d(E\x03\x00\x01IN" +
+		"\x1d(E\x02\x00\x06\x03\x1d(E\x02\x00\x06\x05\x1d(E\x02\x00\x06\x06" +
+		"\x1d(E\x04\x00\x02OUT"
+	if got := string(w.GetWritten()); got != written {
+		t.Errorf("Expected %q, got %q", written, got)
+	}
+}
+
+func TestInfoPaperWidth(t *testing.T) {
+	for setting, width := range map[string]int{"1": 40, "3": 60, "4": 70, "5": 76, "9": 0} {
+		w := NewMockWriter()
+		w.buffer.WriteString("\x20\x02\x05_\x00_\x00_\x00_\x00" +
+			"7'\x03\x1f" + setting + "\x00" + "7'\x05\x1f0\x00" + "7'\x06\x1f1\x00")
+		p, _ := NewPrinter(w)
+		info, err := p.Info(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.PaperWidth != width {
+			t.Errorf("Expected paper width %d for setting %s, got %d", width, setting, info.PaperWidth)
+		}
+	}
+}
+
+func TestInfoErrors(t *testing.T) {
+	for _, script := range []string{
+		"",
+		"\x20\x02\x05_1.00",
+		"\x20\x02\x05x",
+		"\x20\x02\x05_\x00_\x00_\x00_\x00" + "7'\x03\x00",
+	} {
+		w := NewMockWriter()
+		w.buffer.WriteString(script)
+		p, _ := NewPrinter(w)
+		if _, err := p.Info(context.Background()); err == nil {
+			t.Errorf("Expected error for %q", script)
+		}
+
+		// the user setting mode is always left once entered
+		written := string(w.GetWritten())
+		if strings.Contains(written, string(userSettingIn)) && !strings.HasSuffix(written, string(userSettingOut)) {
+			t.Errorf("Expected user setting mode to be left for %q, got %q", script, written)
+		}
+	}
+}
+
+func TestInfoUnanswered(t *testing.T) {
+	conn, printer := net.Pipe()
+	defer conn.Close()
+	defer printer.Close()
+
+	// the printer answers neither the serial number nor the print speed
+	answers := map[string]string{
+		"\x1dI\x01": "\x20", "\x1dI\x02": "\x02", "\x1dI\x03": "\x05",
+		"\x1dIA": "_1.00\x00", "\x1dIB": "_EPSON\x00", "\x1dIC": "_TM-m30\x00",
+		"\x1d(E\x02\x00\x06\x03": "7'\x03\x1f2\x00",
+		"\x1d(E\x02\x00\x06\x05": "7'\x05\x1f0\x00",
+	}
+	written := make(chan string, 1)
+	go func() {
+		var all []byte
+		defer func() { written <- string(all) }()
+		header := make([]byte, 3)
+		for {
+			if _, err := io.ReadFull(printer, header); err != nil {
+				return
+			}
+			req := header
+			if header[1] == '(' {
+				size := make([]byte, 2)
+				io.ReadFull(printer, size)
+				data := make([]byte, int(size[0]))
+				io.ReadFull(printer, data)
+				req = append(append(append([]byte{}, header...), size...), data...)
+			}
+			all = append(all, req...)
+			if a, ok := answers[string(req)]; ok {
+				printer.Write([]byte(a))
+			}
+		}
+	}()
+
+	p, _ := NewPrinter(conn)
+	info, err := p.Info(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.ModelID != 0x20 || info.Model != "TM-m30" || info.PaperWidth != 58 || info.SerialNumber != "" || info.PrintSpeed != 0 {
+		t.Errorf("Unexpected info %+v", info)
+	}
+
+	conn.Close()
+	if got := <-written; !strings.HasSuffix(got, string(userSettingOut)) {
+		t.Errorf("Expected user setting mode to be left, got %q", got)
+	}
+}
@@ -0,0 +1,194 @@
+package escpos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+)
+
+var (
+	// ErrJobDone is returned when committing or discarding a job that was
+	// already committed or discarded.
+	ErrJobDone = errors.New("print job already committed or discarded")
+
+	// ErrJobTooLarge is returned by commands that would grow a job past its
+	// maximum size.
+	ErrJobTooLarge = errors.New("print job too large")
+
+	// ErrJobRead is returned by commands reading from the printer, such as
+	// Status, when called on a job.
+	ErrJobRead = errors.New("cannot read from the printer in a print job")
+)
+
+// Job collects the commands of a print job, such as a whole receipt, and
+// sends them to the printer at once when committed. A job is used like the
+// printer it was created from, and starts with the printer's state and
+// options; commands reading from the printer fail with ErrJobRead. With raster
+// flow control, the job is sent in parts, waiting for the printer to process
+// each band of a raster image before sending the next.
+//
+// The first command error is recorded like with a printer, and returned by
+// Commit.
+type Job struct {
+	*Printer
+
+	// target is the printer the job is committed to
+	target *Printer
+
+	buf  jobBuffer
+	done bool
+}
+
+// jobBuffer is the buffer of a job, limited to max bytes when not zero.
+type jobBuffer struct {
+	bytes.Buffer
+	max int
+
+	// syncs are the offsets where the printer must have processed the data
+	// before the rest is sent, with flow control
+	syncs []int
+}
+
+// Write satisfies the io.Writer interface.
+func (b *jobBuffer) Write(buf []byte) (int, error) {
+	if b.max != 0 && b.Len()+len(buf) > b.max {
+		return 0, ErrJobTooLarge
+	}
+	return b.Buffer.Write(buf)
+}
+
+// Read satisfies the io.Reader interface.
+func (b *jobBuffer) Read([]byte) (int, error) {
+	return 0, ErrJobRead
+}
+
+// NewJob creates a new print job for the printer, taking the printer lock to
+// read its state.
+func (p *Printer) NewJob(opts ...JobOption) (*Job, error) {
+	p.Lock()
+	defer p.Unlock()
+	return p.newJob(opts...)
+}
+
+// newJob creates a new print job for the printer, whose lock must be held.
+func (p *Printer) newJob(opts ...JobOption) (*Job, error) {
+	j := &Job{target: p}
+	j.Printer = p.jobPrinter(&j.buf)
+
+	// apply opts
+	for _, o := range opts {
+		if err := o(j); err != nil {
+			return nil, err
+		}
+	}
+
+	return j, nil
+}
+
+// Len returns the size of the commands collected so far.
+func (j *Job) Len() int {
+	return j.buf.Len()
+}
+
+// Commit sends the job to the printer in a single write, or one for each
+// raster band with flow control, holding the printer lock, and flushes the
+// printer's writer when it is buffered. Other goroutines sharing the printer
+// should hold the lock while using it directly. Nothing is sent when a command
+// of the job failed, and its error is returned. Once sent, the printer takes
+// on the state left by the job.
+func (j *Job) Commit() error {
+	return j.CommitContext(context.Background())
+}
+
+// CommitContext is like Commit, but gives up when ctx is done.
+func (j *Job) CommitContext(ctx context.Context) error {
+	j.target.Lock()
+	defer j.target.Unlock()
+	return j.commit(ctx)
+}
+
+// commit sends the job to the printer, whose lock must be held.
+func (j *Job) commit(ctx context.Context) error {
+	if j.done {
+		return ErrJobDone
+	}
+	j.done = true
+	if err := j.Err(); err != nil {
+		return err
+	}
+
+	p := j.target
+	err := p.Do(ctx, func() error {
+		data, start := j.buf.Bytes(), 0
+		for _, end := range j.buf.syncs {
+			if err := p.writeFlush(data[start:end]); err != nil {
+				return err
+			}
+			if err := p.waitProcessed(); err != nil {
+				return err
+			}
+			start = end
+		}
+		return p.writeFlush(data[start:])
+	})
+	j.buf.Reset()
+	if err != nil {
+		return err
+	}
+
+	p.copyState(j.Printer)
+	return nil
+}
+
+// Discard drops the commands of the job, leaving the printer unchanged.
+func (j *Job) Discard() error {
+	if j.done {
+		return ErrJobDone
+	}
+	j.done = true
+	j.buf.Reset()
+	return nil
+}
+
+// writeFlush writes buf to the printer, and flushes the printer's writer when
+// it is buffered.
+func (p *Printer) writeFlush(buf []byte) error {
+	if err := p.write(buf); err != nil {
+		return err
+	}
+	if f, ok := p.w.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			p.err = timeoutError(p.ctx, "write", err)
+			return p.err
+		}
+	}
+	return nil
+}
+
+// jobPrinter returns a printer writing to w, with the state and options of p.
+func (p *Printer) jobPrinter(w *jobBuffer) *Printer {
+	renderer := *p.renderer
+	jp := &Printer{
+		w:              w,
+		renderer:       &renderer,
+		imageOpts:      p.imageOpts,
+		bandHeight:     p.bandHeight,
+		flowControl:    p.flowControl,
+		extendedStatus: p.extendedStatus,
+		profile:        p.profile,
+	}
+	jp.copyState(p)
+	return jp
+}
+
+// copyState copies the printing state of src, as changed by the commands sent
+// to the printer.
+func (p *Printer) copyState(src *Printer) {
+	p.width, p.height = src.width, src.height
+	p.underline, p.emphasize = src.underline, src.emphasize
+	p.upsidedown, p.rotate, p.align = src.upsidedown, src.rotate, src.align
+	p.reverse, p.smooth = src.reverse, src.smooth
+	p.codePage, p.codePages = src.codePage, src.codePages
+	p.replacement = src.replacement
+	p.userChars = src.userChars
+}
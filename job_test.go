@@ -0,0 +1,149 @@
+package escpos
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/morezig/goescpos/raster"
+)
+
+// countingWriter counts the writes to a MockWriter.
+type countingWriter struct {
+	*MockWriter
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.MockWriter.Write(p)
+}
+
+func TestJobCommit(t *testing.T) {
+	w := &countingWriter{MockWriter: NewMockWriter()}
+	p, _ := NewPrinter(w)
+
+	job, err := p.NewJob()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	job.Init()
+	job.SetEmphasize(1)
+	job.WriteText("hello")
+	job.Cut()
+	if w.writes != 0 {
+		t.Fatalf("Expected nothing written before commit, got %q", w.GetWritten())
+	}
+
+	expected := "\x1b@\x1bG\x01hello\x1dVA0"
+	if job.Len() != len(expected) {
+		t.Errorf("Expected job length %d, got %d", len(expected), job.Len())
+	}
+	if err := job.Commit(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := string(w.GetWritten()); w.writes != 1 || got != expected {
+		t.Errorf("Expected %q in a single write, got %q in %d", expected, got, w.writes)
+	}
+
+	// the printer takes on the state of the job
+	if p.emphasize != 1 {
+		t.Error("Expected printer to be emphasized")
+	}
+	if err := job.Commit(); err != ErrJobDone {
+		t.Errorf("Expected ErrJobDone, got %v", err)
+	}
+}
+
+func TestJobDiscard(t *testing.T) {
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+
+	job, _ := p.NewJob()
+	job.SetUnderline(1)
+	if err := job.Discard(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(w.GetWritten()) != 0 || p.underline != 0 {
+		t.Errorf("Expected printer unchanged, got %q", w.GetWritten())
+	}
+	if err := job.Commit(); err != ErrJobDone {
+		t.Errorf("Expected ErrJobDone, got %v", err)
+	}
+}
+
+func TestJobErrors(t *testing.T) {
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+
+	if _, err := p.NewJob(WithMaxJobSize(0)); err == nil {
+		t.Error("Expected error for invalid job size")
+	}
+
+	job, _ := p.NewJob(WithMaxJobSize(4))
+	if err := job.write([]byte("abc")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := job.write([]byte("de")); err != ErrJobTooLarge {
+		t.Errorf("Expected ErrJobTooLarge, got %v", err)
+	}
+	if err := job.Commit(); err != ErrJobTooLarge {
+		t.Errorf("Expected ErrJobTooLarge, got %v", err)
+	}
+	if len(w.GetWritten()) != 0 {
+		t.Errorf("Expected nothing written, got %q", w.GetWritten())
+	}
+
+	job, _ = p.NewJob()
+	if _, err := job.Status(context.Background()); err != ErrJobRead {
+		t.Errorf("Expected ErrJobRead, got %v", err)
+	}
+}
+
+func TestJobFlowControl(t *testing.T) {
+	w := &countingWriter{MockWriter: NewMockWriter()}
+	w.buffer.WriteString("\x00")
+	p, _ := NewPrinter(w, WithFlowControl(), WithRasterBandHeight(1))
+
+	job, _ := p.NewJob()
+	if err := job.Raster(8, 2, 1, []byte{1, 2}, raster.BitImage); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := job.Commit(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// the printer is queried in between the bands
+	expected := "\x1dv0\x00\x01\x00\x01\x00\x01" + "\x1dr\x01" + "\x1dv0\x00\x01\x00\x01\x00\x02"
+	if got := string(w.GetWritten()); w.writes != 3 || got != expected {
+		t.Errorf("Expected %q in 3 writes, got %q in %d", expected, got, w.writes)
+	}
+}
+
+func TestJobConcurrentCommits(t *testing.T) {
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+
+	var wg sync.WaitGroup
+	for _, s := range []string{"first", "second", "third"} {
+		wg.Add(1)
+		go func(s string) {
+			defer wg.Done()
+			job, _ := p.NewJob()
+			job.write([]byte(s))
+			job.Cut()
+			if err := job.Commit(); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	written := w.GetWritten()
+	for _, s := range []string{"first", "second", "third"} {
+		if !bytes.Contains(written, []byte(s+"\x1dVA0")) {
+			t.Errorf("Expected job %q in one piece, got %q", s, written)
+		}
+	}
+}
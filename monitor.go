@@ -0,0 +1,294 @@
+package escpos
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrMonitorClosed is returned by reads from a printer whose status monitor
+// stopped.
+var ErrMonitorClosed = errors.New("status monitor closed")
+
+// asbEnable is the GS a argument enabling the drawer, online, error, paper
+// sensor and panel switch status.
+const asbEnable = 0x4f
+
+// StatusEvent is a change of the printer status, as sent by the printer with
+// Automatic Status Back (ASB).
+type StatusEvent struct {
+	// Status is the new status of the printer.
+	Status PrinterStatus
+
+	// Previous is the status before the change, the zero status for the
+	// first event.
+	Previous PrinterStatus
+
+	// Time is when the status was received.
+	Time time.Time
+}
+
+// Monitor reads the status changes the printer sends with Automatic Status
+// Back (ASB), and publishes them to its subscribers. While a monitor runs, it
+// is the only reader of the printer connection, and other responses of the
+// printer are passed on to the printer's reads.
+type Monitor struct {
+	p *Printer
+
+	// resp are the bytes read that are not part of an ASB packet
+	resp chan byte
+	done chan struct{}
+
+	// exited is closed when the reader returns
+	exited chan struct{}
+
+	mu     sync.Mutex
+	subs   map[chan StatusEvent]struct{}
+	status PrinterStatus
+	seen   bool
+	err    error
+}
+
+// StartMonitor enables Automatic Status Back (GS a) and starts reading the
+// status changes sent by the printer, until the monitor is closed or the
+// printer connection fails.
+func (p *Printer) StartMonitor() (*Monitor, error) {
+	p.monitorMu.Lock()
+	defer p.monitorMu.Unlock()
+	if p.monitor != nil {
+		return nil, errors.New("status monitor already running")
+	}
+
+	if err := p.write([]byte{0x1d, 'a', asbEnable}); err != nil {
+		return nil, err
+	}
+
+	m := &Monitor{
+		p:      p,
+		resp:   make(chan byte, 256),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+		subs:   make(map[chan StatusEvent]struct{}),
+	}
+	p.monitor = m
+	go m.run()
+	return m, nil
+}
+
+// Subscribe returns a channel receiving the status events, and a function
+// ending the subscription. Events are dropped for subscribers that fall
+// behind; Status always returns the latest status. The channel is closed
+// when the monitor stops.
+func (m *Monitor) Subscribe() (<-chan StatusEvent, func()) {
+	ch := make(chan StatusEvent, 16)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case <-m.done:
+		close(ch)
+		return ch, func() {}
+	default:
+	}
+	m.subs[ch] = struct{}{}
+
+	return ch, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if _, ok := m.subs[ch]; ok {
+			delete(m.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Status returns the latest status sent by the printer.
+func (m *Monitor) Status() PrinterStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// Done returns a channel closed when the monitor stops.
+func (m *Monitor) Done() <-chan struct{} {
+	return m.done
+}
+
+// Err returns the error that stopped the monitor, if any.
+func (m *Monitor) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// Close disables Automatic Status Back and stops the monitor. When the
+// printer connection does not support read deadlines, the read in progress
+// goes on in the background, and the byte it reads is lost.
+func (m *Monitor) Close() error {
+	err := m.p.write([]byte{0x1d, 'a', 0})
+	m.stop(ErrMonitorClosed)
+
+	if d, ok := m.p.w.(readDeadliner); ok && d.SetReadDeadline(time.Now()) == nil {
+		<-m.exited
+		d.SetReadDeadline(time.Time{})
+	}
+	return err
+}
+
+// stop detaches the monitor from the printer and closes the subscriptions,
+// recording err as the reason unless already stopped.
+func (m *Monitor) stop(err error) {
+	m.p.monitorMu.Lock()
+	if m.p.monitor == m {
+		m.p.monitor = nil
+	}
+	m.p.monitorMu.Unlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case <-m.done:
+		return
+	default:
+	}
+	m.err = err
+	for ch := range m.subs {
+		close(ch)
+	}
+	m.subs = nil
+	close(m.done)
+}
+
+// run reads from the printer until the connection fails, publishing ASB
+// packets and passing the other bytes on. Blocks of data (the responses to
+// GS I and GS ( E, for instance) start with 0x37 or 0x5f and end with NUL.
+func (m *Monitor) run() {
+	defer close(m.exited)
+
+	b := make([]byte, 1)
+	read := func() bool {
+		if _, err := m.p.w.Read(b); err != nil {
+			m.stop(err)
+			return false
+		}
+		return true
+	}
+	pass := func() bool {
+		select {
+		case m.resp <- b[0]:
+			return true
+		case <-m.done:
+			return false
+		}
+	}
+
+	for read() {
+		switch {
+		case b[0]&0x93 == 0x10:
+			// ASB packet, 0xx1xx00 followed by 3 bytes
+			var asb [4]byte
+			asb[0] = b[0]
+			for i := 1; i < len(asb); i++ {
+				if !read() {
+					return
+				}
+				asb[i] = b[0]
+			}
+			m.publish(decodeASB(asb))
+
+		case b[0] == 0x37 || b[0] == 0x5f:
+			for {
+				if !pass() {
+					return
+				}
+				if b[0] == 0 {
+					break
+				}
+				if !read() {
+					return
+				}
+			}
+
+		default:
+			if !pass() {
+				return
+			}
+		}
+	}
+}
+
+// publish records the status st and sends an event to the subscribers when
+// it changed.
+func (m *Monitor) publish(st PrinterStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.seen && st == m.status {
+		return
+	}
+
+	ev := StatusEvent{Status: st, Previous: m.status, Time: time.Now()}
+	m.status, m.seen = st, true
+	for ch := range m.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// read reads len(buf) bytes passed on by the monitor, giving up when ctx is
+// done.
+func (m *Monitor) read(ctx context.Context, buf []byte) error {
+	for i := range buf {
+		select {
+		case buf[i] = <-m.resp:
+		case <-m.done:
+			return ErrMonitorClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// drain discards the bytes passed on by the monitor that no read is waiting
+// for, and when wait is true, those arriving until none arrive for
+// drainTimeout.
+func (m *Monitor) drain(wait bool) {
+	for {
+		if !wait {
+			select {
+			case <-m.resp:
+			default:
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-m.resp:
+		case <-time.After(drainTimeout):
+			return
+		}
+	}
+}
+
+// decodeASB returns the printer status of an ASB packet.
+func decodeASB(asb [4]byte) PrinterStatus {
+	return PrinterStatus{
+		DrawerPin:            asb[0]&0x04 != 0,
+		Online:               asb[0]&0x08 == 0,
+		CoverOpen:            asb[0]&0x20 != 0,
+		PaperFeeding:         asb[0]&0x40 != 0,
+		WaitingOnline:        asb[1]&0x01 != 0,
+		FeedButton:           asb[1]&0x02 != 0,
+		MechanicalError:      asb[1]&0x04 != 0,
+		CutterError:          asb[1]&0x08 != 0,
+		UnrecoverableError:   asb[1]&0x20 != 0,
+		AutoRecoverableError: asb[1]&0x40 != 0,
+		Error:                asb[1]&0x6c != 0,
+		PaperNearEnd:         asb[2]&0x03 != 0,
+		PaperEnd:             asb[2]&0x0c != 0,
+	}
+}
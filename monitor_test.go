@@ -0,0 +1,119 @@
+package escpos
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMonitor(t *testing.T) {
+	conn, printer := net.Pipe()
+	defer conn.Close()
+	defer printer.Close()
+
+	// the printer answers the real-time status requests, with an ASB packet
+	// after the first answer
+	answers := make(chan []byte, 4)
+	go func() {
+		buf := make([]byte, 3)
+		for {
+			if _, err := io.ReadFull(printer, buf); err != nil {
+				return
+			}
+			if buf[0] != 0x10 {
+				continue
+			}
+			select {
+			case a := <-answers:
+				printer.Write(a)
+			default:
+			}
+		}
+	}()
+
+	p, _ := NewPrinter(conn)
+	m, err := p.StartMonitor()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := p.StartMonitor(); err == nil {
+		t.Error("Expected error for second monitor")
+	}
+	events, _ := m.Subscribe()
+
+	next := func() StatusEvent {
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for status event")
+		}
+		return StatusEvent{}
+	}
+
+	// the first status, an unchanged status and a change
+	printer.Write([]byte{0x14, 0x00, 0x00, 0x00})
+	printer.Write([]byte{0x14, 0x00, 0x00, 0x00})
+	printer.Write([]byte{0x38, 0x00, 0x04, 0x00})
+	if ev := next(); ev.Status != (PrinterStatus{Online: true, DrawerPin: true}) {
+		t.Errorf("Unexpected first status %+v", ev.Status)
+	}
+	ev := next()
+	if expected := (PrinterStatus{CoverOpen: true, PaperEnd: true}); ev.Status != expected || !ev.Previous.DrawerPin {
+		t.Errorf("Unexpected status change %+v", ev)
+	}
+	if !m.Status().CoverOpen {
+		t.Error("Expected latest status to have the cover open")
+	}
+
+	// real-time status replies are passed on, even around an ASB packet
+	for _, a := range []string{"\x12\x10\x00\x00\x00", "\x12", "\x12", "\x16"} {
+		answers <- []byte(a)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	st, err := p.Status(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !st.Online || !st.PaperNearEnd {
+		t.Errorf("Unexpected status %+v", st)
+	}
+	if ev := next(); ev.Status != (PrinterStatus{Online: true}) {
+		t.Errorf("Unexpected status %+v", ev.Status)
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := <-events; ok {
+		t.Error("Expected events to be closed")
+	}
+	if m.Err() != ErrMonitorClosed {
+		t.Errorf("Expected ErrMonitorClosed, got %v", m.Err())
+	}
+}
+
+func TestMonitorConnectionError(t *testing.T) {
+	w := NewMockWriter()
+	w.buffer.Write([]byte{0x10, 0x00, 0x00, 0x00})
+	p, _ := NewPrinter(w)
+	m, err := p.StartMonitor()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	select {
+	case <-m.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for monitor to stop")
+	}
+	if m.Err() != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", m.Err())
+	}
+	if got := string(w.GetWritten()); got != "\x1da\x4f" {
+		t.Errorf("Expected %q, got %q", "\x1da\x4f", got)
+	}
+}
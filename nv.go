@@ -0,0 +1,227 @@
+package escpos
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strconv"
+)
+
+// NVKey is the key code of graphics stored in the non-volatile memory of the
+// printer. Printers accept key codes from 32 to 126.
+type NVKey [2]byte
+
+// String satisfies the fmt.Stringer interface.
+func (k NVKey) String() string {
+	return string(k[:])
+}
+
+// ErrNVKeyList is returned when the printer's list of NV graphics key codes
+// cannot be read.
+var ErrNVKeyList = errors.New("invalid NV graphics key code list")
+
+// nvSend sends the NV or download graphics function fn with data, with GS ( L
+// or, when the data is too long, GS 8 L.
+func (p *Printer) nvSend(fn byte, data []byte) error {
+	l := len(data) + 2
+	if l <= 0xffff {
+		if err := p.write([]byte{0x1d, '(', 'L', byte(l), byte(l >> 8), '0', fn}); err != nil {
+			return err
+		}
+	} else {
+		if err := p.write([]byte{0x1d, '8', 'L', byte(l), byte(l >> 8), byte(l >> 16), byte(l >> 24), '0', fn}); err != nil {
+			return err
+		}
+	}
+	return p.write(data)
+}
+
+// StoreNVGraphics stores img, converted to a raster with opts, in the NV
+// graphics memory of the printer with the key code key (GS ( L function 67).
+// Writing to the NV memory is slow and wears it out, so graphics should only
+// be stored when they change.
+func (p *Printer) StoreNVGraphics(key NVKey, img image.Image, opts *ImageOptions) error {
+	o := p.imageOptions(opts)
+
+	data, width, lineWidth := o.Converter.ToRaster(img)
+	return p.StoreNVGraphicsRaster(key, width, rasterSize(data, lineWidth), data)
+}
+
+// StoreNVGraphicsRaster stores the width×height raster planes, one for each
+// colour from the first, in the NV graphics memory of the printer with the
+// key code key (GS ( L function 67).
+func (p *Printer) StoreNVGraphicsRaster(key NVKey, width, height int, planes ...[]byte) error {
+	data, err := graphicsDefinition(key, width, height, planes)
+	if err != nil {
+		return err
+	}
+	return p.nvSend('C', data)
+}
+
+// graphicsDefinition returns the parameters defining the width×height
+// raster planes with the key code key, as used by the NV and download
+// graphics functions.
+func graphicsDefinition(key NVKey, width, height int, planes [][]byte) ([]byte, error) {
+	if len(planes) == 0 || len(planes) > 4 {
+		return nil, errors.New("graphics must have 1 to 4 color planes")
+	}
+	size := (width + 7) / 8 * height
+	for _, plane := range planes {
+		if len(plane) != size {
+			return nil, fmt.Errorf("graphics plane must be %d bytes", size)
+		}
+	}
+
+	data := []byte{
+		'0',            // a -- raster format
+		key[0], key[1], // kc1, kc2
+		byte(len(planes)),             // b -- number of colors
+		byte(width), byte(width >> 8), // xl, xh
+		byte(height), byte(height >> 8), // yl, yh
+	}
+	for i, plane := range planes {
+		data = append(data, graphicsColor(ColorFirst+Color(i)))
+		data = append(data, plane...)
+	}
+	return data, nil
+}
+
+// PrintNVGraphics prints the graphics stored in the NV memory of the printer
+// with the key code key (GS ( L function 69).
+func (p *Printer) PrintNVGraphics(key NVKey) error {
+	return p.nvSend('E', []byte{key[0], key[1], 1, 1})
+}
+
+// DeleteNVGraphics deletes the graphics stored in the NV memory of the
+// printer with the key code key (GS ( L function 66).
+func (p *Printer) DeleteNVGraphics(key NVKey) error {
+	return p.nvSend('B', []byte{key[0], key[1]})
+}
+
+// DeleteAllNVGraphics deletes all the graphics stored in the NV memory of the
+// printer (GS ( L function 65).
+func (p *Printer) DeleteAllNVGraphics() error {
+	return p.nvSend('A', []byte("CLR"))
+}
+
+// NVGraphicsKeys returns the key codes of the graphics stored in the NV
+// memory of the printer (GS ( L function 64). The printer's writer must not
+// be buffered.
+func (p *Printer) NVGraphicsKeys() ([]NVKey, error) {
+	p.discardStale()
+	if err := p.nvSend('@', []byte("KC")); err != nil {
+		return nil, err
+	}
+
+	var keys []NVKey
+	for {
+		// header, identifier and status
+		header := make([]byte, 3)
+		if err := p.readFull(p.currentContext(), header); err != nil {
+			return nil, err
+		}
+		if header[0] != 0x37 || header[1] != 0x72 || (header[2] != 0x40 && header[2] != 0x41) {
+			return nil, ErrNVKeyList
+		}
+
+		// key codes, up to the NUL
+		var codes []byte
+		b := make([]byte, 1)
+		for {
+			if err := p.readFull(p.currentContext(), b); err != nil {
+				return nil, err
+			}
+			if b[0] == 0 {
+				break
+			}
+			codes = append(codes, b[0])
+		}
+		if len(codes)%2 != 0 {
+			return nil, ErrNVKeyList
+		}
+		for i := 0; i < len(codes); i += 2 {
+			keys = append(keys, NVKey{codes[i], codes[i+1]})
+		}
+
+		// the list continues in another block once acknowledged
+		if header[2] == 0x40 {
+			return keys, nil
+		}
+		if err := p.write([]byte{0x06}); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// StoreNVBitImages replaces the NV bit images stored in the printer with imgs,
+// converted to rasters with opts (FS q). NV bit images are superseded by NV
+// graphics, but are the only NV images supported by older printers. Images
+// are numbered from 1, in order.
+func (p *Printer) StoreNVBitImages(opts *ImageOptions, imgs ...image.Image) error {
+	if len(imgs) == 0 || len(imgs) > 255 {
+		return errors.New("NV bit images must number 1 to 255")
+	}
+	o := p.imageOptions(opts)
+
+	buf := []byte{0x1c, 'q', byte(len(imgs))}
+	for _, img := range imgs {
+		data, width, lineWidth := o.Converter.ToRaster(img)
+		height := rasterSize(data, lineWidth)
+
+		// column format, in units of 8 dots
+		x, y := (width+7)/8, (height+7)/8
+		buf = append(buf, byte(x), byte(x>>8), byte(y), byte(y>>8))
+		buf = append(buf, toColumns(data, lineWidth, width, height, x*8, y)...)
+	}
+
+	return p.write(buf)
+}
+
+// toColumns returns the first columns of the width×height raster data,
+// packed in lines of lineWidth bytes, in column format: from left to right,
+// rowBytes bytes for each column from top to bottom. Dots outside of the
+// raster are blank.
+func toColumns(data []byte, lineWidth, width, height, columns, rowBytes int) []byte {
+	buf := make([]byte, 0, columns*rowBytes)
+	for col := 0; col < columns; col++ {
+		for row := 0; row < rowBytes*8; row += 8 {
+			var c byte
+			for i := 0; i < 8; i++ {
+				if col < width && row+i < height && data[(row+i)*lineWidth+col/8]&(0x80>>uint(col%8)) != 0 {
+					c |= 0x80 >> uint(i)
+				}
+			}
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+// PrintNVBitImage prints the NV bit image n (FS p), in mode m: 0 for normal,
+// 1 for double width, 2 for double height and 3 for quadruple size.
+func (p *Printer) PrintNVBitImage(n, m byte) error {
+	if m > 3 {
+		return fmt.Errorf("invalid NV bit image mode: %d", m)
+	}
+	return p.write([]byte{0x1c, 'p', n, m})
+}
+
+// logo prints the NV graphics with the key codes of an ePOS logo element.
+func (p *Printer) logo(params map[string]string) error {
+	var key NVKey
+	for i, name := range []string{"key1", "key2"} {
+		k, err := strconv.ParseUint(params[name], 10, 8)
+		if err != nil {
+			return fmt.Errorf("invalid logo %s: %q", name, params[name])
+		}
+		key[i] = byte(k)
+	}
+
+	print := func() error {
+		return p.PrintNVGraphics(key)
+	}
+	if align, ok := params["align"]; ok {
+		return p.withAlign(align, print)
+	}
+	return print()
+}
@@ -0,0 +1,101 @@
+package escpos
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestNVGraphics(t *testing.T) {
+	key := NVKey{'A', '1'}
+	testCases := []struct {
+		name     string
+		fn       func(p *Printer) error
+		expected string
+	}{
+		{"Store", func(p *Printer) error { return p.StoreNVGraphicsRaster(key, 8, 2, []byte{0xf0, 0x0f}) },
+			"\x1d(L\x0d\x000C0A1\x01\x08\x00\x02\x001\xf0\x0f"},
+		{"Store two colors", func(p *Printer) error { return p.StoreNVGraphicsRaster(key, 8, 1, []byte{0x80}, []byte{0x01}) },
+			"\x1d(L\x0e\x000C0A1\x02\x08\x00\x01\x001\x802\x01"},
+		{"Print", func(p *Printer) error { return p.PrintNVGraphics(key) },
+			"\x1d(L\x06\x000EA1\x01\x01"},
+		{"Delete", func(p *Printer) error { return p.DeleteNVGraphics(key) },
+			"\x1d(L\x04\x000BA1"},
+		{"Delete all", func(p *Printer) error { return p.DeleteAllNVGraphics() },
+			"\x1d(L\x05\x000ACLR"},
+		{"Print bit image", func(p *Printer) error { return p.PrintNVBitImage(1, 3) },
+			"\x1cp\x01\x03"},
+		{"Logo", func(p *Printer) error { return p.WriteNode("logo", map[string]string{"key1": "65", "key2": "49"}, "") },
+			"\x1d(L\x06\x000EA1\x01\x01"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewMockWriter()
+			p, _ := NewPrinter(w)
+			if err := tc.fn(p); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := string(w.GetWritten()); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestNVGraphicsErrors(t *testing.T) {
+	p, _ := NewPrinter(NewMockWriter())
+	if err := p.StoreNVGraphicsRaster(NVKey{'A', '1'}, 8, 2, []byte{0xff}); err == nil {
+		t.Error("Expected error for short plane")
+	}
+	if err := p.PrintNVBitImage(1, 4); err == nil {
+		t.Error("Expected error for invalid mode")
+	}
+	if err := p.WriteNode("logo", map[string]string{"key1": "x", "key2": "1"}, ""); err == nil {
+		t.Error("Expected error for invalid logo key")
+	}
+}
+
+func TestStoreNVBitImages(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 9, 1))
+	img.SetGray(0, 0, color.Gray{0xff})
+	img.SetGray(8, 0, color.Gray{0xff})
+
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+	if err := p.StoreNVBitImages(nil, img); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "\x1cq\x01\x02\x00\x01\x00" +
+		"\x80\x00\x00\x00\x00\x00\x00\x00" +
+		"\x80\x00\x00\x00\x00\x00\x00\x00"
+	if got := string(w.GetWritten()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestNVGraphicsKeys(t *testing.T) {
+	w := NewMockWriter()
+	w.buffer.WriteString("\x37\x72\x41A1B2\x00\x37\x72\x40C3\x00")
+	p, _ := NewPrinter(w)
+
+	keys, err := p.NVGraphicsKeys()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []NVKey{{'A', '1'}, {'B', '2'}, {'C', '3'}}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+	if got, exp := string(w.GetWritten()), "\x1d(L\x04\x000@KC\x06"; got != exp {
+		t.Errorf("Expected %q, got %q", exp, got)
+	}
+
+	w = NewMockWriter()
+	w.buffer.WriteString("\x37\x22\x40\x00")
+	p, _ = NewPrinter(w)
+	if _, err := p.NVGraphicsKeys(); err != ErrNVKeyList {
+		t.Errorf("Expected ErrNVKeyList, got %v", err)
+	}
+}
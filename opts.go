@@ -1,5 +1,10 @@
 package escpos
 
+import (
+	"errors"
+	"time"
+)
+
 // ServerOption is a server option.
 type ServerOption func(*Server) error
 
@@ -10,3 +15,116 @@ func WithLog(f func(string, ...interface{})) ServerOption {
 		return nil
 	}
 }
+
+// WithStatus is a server option to read the printer status after each job,
+// and report it in the response. The printer connection must answer real-time
+// status requests (DLE EOT), and support read deadlines; on connections that
+// do not, such as device files, jobs are reported without a status.
+func WithStatus() ServerOption {
+	return func(s *Server) error {
+		s.status = true
+		return nil
+	}
+}
+
+// WithJobTimeout is a server option to give up on jobs that are not sent to
+// the printer within d. Such jobs fail with the EX_TIMEOUT code. A write in
+// progress is only interrupted when the printer connection supports write
+// deadlines, such as a network connection; on a device file, the timeout is
+// checked before each write, and a hung write keeps blocking the server.
+func WithJobTimeout(d time.Duration) ServerOption {
+	return func(s *Server) error {
+		if d < 0 {
+			return errors.New("must supply valid job timeout")
+		}
+		s.timeout = d
+		return nil
+	}
+}
+
+// PrinterOption is a printer option.
+type PrinterOption func(*Printer) error
+
+// WithRenderOptions is a printer option to set the options used to print text
+// as an image.
+func WithRenderOptions(opts RenderOptions) PrinterOption {
+	return func(p *Printer) error {
+		r, err := NewTextRenderer(opts)
+		if err != nil {
+			return err
+		}
+		p.renderer = r
+		p.renderWidth = true
+		return nil
+	}
+}
+
+// WithImageOptions is a printer option to set the options used to print
+// images when none are given.
+func WithImageOptions(opts ImageOptions) PrinterOption {
+	return func(p *Printer) error {
+		p.imageOpts = opts
+		return nil
+	}
+}
+
+// WithRasterBandHeight is a printer option to set the number of rows sent
+// with each GS v 0 command. Zero sends whole images in a single command.
+func WithRasterBandHeight(rows int) PrinterOption {
+	return func(p *Printer) error {
+		return p.SetRasterBandHeight(rows)
+	}
+}
+
+// WithFlowControl is a printer option to wait for the printer to process each
+// band of a raster image before sending the next.
+func WithFlowControl() PrinterOption {
+	return func(p *Printer) error {
+		p.flowControl = true
+		return nil
+	}
+}
+
+// WithExtendedStatus is a printer option to also read the ink and peeler
+// status (DLE EOT 7 and 8) in Status, on printers supporting them.
+func WithExtendedStatus() PrinterOption {
+	return func(p *Printer) error {
+		p.extendedStatus = true
+		return nil
+	}
+}
+
+// WithProfile is a printer option to set the capability profile of the
+// printer, so that commands it does not support are avoided or emulated, and
+// images and text are sized to its printable width. Text keeps the width of
+// the render options, when given.
+func WithProfile(pr *Profile) PrinterOption {
+	return func(p *Printer) error {
+		if pr == nil {
+			return errors.New("must supply valid profile")
+		}
+		p.profile = pr
+		if !p.renderWidth && pr.Width > 0 {
+			renderer := *p.renderer
+			renderer.opts.Width = pr.Width
+			p.renderer = &renderer
+		}
+		return nil
+	}
+}
+
+// JobOption is a print job option.
+type JobOption func(*Job) error
+
+// WithMaxJobSize is a job option to limit the size of the commands collected
+// by the job to n bytes. Commands that would exceed it fail with
+// ErrJobTooLarge.
+func WithMaxJobSize(n int) JobOption {
+	return func(j *Job) error {
+		if n <= 0 {
+			return errors.New("must supply valid job size")
+		}
+		j.buf.max = n
+		return nil
+	}
+}
@@ -0,0 +1,231 @@
+package escpos
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strconv"
+
+	"github.com/morezig/goescpos/raster"
+)
+
+// Direction is the print direction in page mode, and the corner of the print
+// area where printing starts.
+type Direction byte
+
+// Directions.
+const (
+	// DirectionLeftToRight starts at the upper left corner.
+	DirectionLeftToRight Direction = iota
+
+	// DirectionBottomToTop starts at the lower left corner.
+	DirectionBottomToTop
+
+	// DirectionRightToLeft starts at the lower right corner.
+	DirectionRightToLeft
+
+	// DirectionTopToBottom starts at the upper right corner.
+	DirectionTopToBottom
+)
+
+// directions are the ePOS-Print direction names.
+var directions = map[string]Direction{
+	"left_to_right": DirectionLeftToRight,
+	"bottom_to_top": DirectionBottomToTop,
+	"right_to_left": DirectionRightToLeft,
+	"top_to_bottom": DirectionTopToBottom,
+}
+
+// PageMode lays out a page in page mode, where data is placed in a print
+// area and printed all at once. It is created with BeginPage, and ended with
+// End or Discard, after which the printer is back in standard mode.
+type PageMode struct {
+	p *Printer
+
+	// width and height are the size of the print area, once set
+	width, height int
+}
+
+// BeginPage switches the printer to page mode (ESC L).
+func (p *Printer) BeginPage() (*PageMode, error) {
+	if !p.supports(FeaturePageMode) {
+		return nil, p.unsupported(FeaturePageMode)
+	}
+	if err := p.write([]byte{0x1b, 'L'}); err != nil {
+		return nil, err
+	}
+	return &PageMode{p: p}, nil
+}
+
+// Area sets the print area of the page (ESC W), in dots from the upper left
+// corner of the printable area.
+func (pg *PageMode) Area(x, y, width, height int) error {
+	if err := pg.p.write([]byte{
+		0x1b, 'W',
+		byte(x), byte(x >> 8), byte(y), byte(y >> 8),
+		byte(width), byte(width >> 8), byte(height), byte(height >> 8),
+	}); err != nil {
+		return err
+	}
+	pg.width, pg.height = width, height
+	return nil
+}
+
+// Direction sets the print direction of the print area (ESC T).
+func (pg *PageMode) Direction(d Direction) error {
+	if d > DirectionTopToBottom {
+		return fmt.Errorf("invalid page direction: %d", d)
+	}
+	return pg.p.write([]byte{0x1b, 'T', byte(d)})
+}
+
+// Position moves the print position to x, y in the print area, relative to
+// the starting corner of the print direction (ESC $ and GS $).
+func (pg *PageMode) Position(x, y int) error {
+	if err := pg.p.SendMoveX(uint16(x)); err != nil {
+		return err
+	}
+	return pg.p.SendMoveY(uint16(y))
+}
+
+// Text writes text at x, y in the print area.
+func (pg *PageMode) Text(x, y int, text string) error {
+	if err := pg.Position(x, y); err != nil {
+		return err
+	}
+	return pg.p.Text(map[string]string{}, text)
+}
+
+// Image prints img, converted to a raster with opts, at x, y in the print
+// area.
+func (pg *PageMode) Image(x, y int, img image.Image, opts *ImageOptions) error {
+	if err := pg.Position(x, y); err != nil {
+		return err
+	}
+	return pg.p.PrintImageData(img, opts)
+}
+
+// Barcode prints a barcode at x, y in the print area.
+func (pg *PageMode) Barcode(x, y int, typ BarcodeType, data string, opts *BarcodeOptions) error {
+	if err := pg.Position(x, y); err != nil {
+		return err
+	}
+	return pg.p.Barcode(typ, data, opts)
+}
+
+// VerticalLine draws a vertical ruled line at dot x of the print area, over
+// the whole height of the area, as a raster image in the mode supported by the
+// printer. The print area must be set first.
+func (pg *PageMode) VerticalLine(x int, style LineStyle) error {
+	if pg.height == 0 {
+		return errors.New("vertical line requires a print area")
+	}
+	if x < 0 || x >= pg.width {
+		return fmt.Errorf("invalid vertical line position: %d", x)
+	}
+	if style < LineThin || style > LineThickDouble {
+		return fmt.Errorf("invalid line style: %d", style)
+	}
+
+	// the columns of the line are the rows of a horizontal line
+	cols := style.rows()
+	lineWidth := (len(cols) + 7) / 8
+	data := make([]byte, lineWidth*pg.height)
+	for y := 0; y < pg.height; y++ {
+		for x, on := range cols {
+			if on {
+				data[y*lineWidth+x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+	}
+
+	if err := pg.Position(x, 0); err != nil {
+		return err
+	}
+	return pg.p.Raster(len(cols), pg.height, lineWidth, data, raster.BitImage)
+}
+
+// Print prints the page, staying in page mode (ESC FF).
+func (pg *PageMode) Print() error {
+	return pg.p.write([]byte{0x1b, 0x0c})
+}
+
+// End prints the page and returns to standard mode (FF).
+func (pg *PageMode) End() error {
+	return pg.p.write([]byte{0x0c})
+}
+
+// Discard deletes the page without printing it (CAN), and returns to
+// standard mode (ESC S).
+func (pg *PageMode) Discard() error {
+	return pg.p.write([]byte{0x18, 0x1b, 'S'})
+}
+
+// atoi returns the integer attribute name of params.
+func atoi(params map[string]string, name string) (int, error) {
+	i, err := strconv.Atoi(params[name])
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %q", name, params[name])
+	}
+	return i, nil
+}
+
+// writePage writes an ePOS page element, with its children, in page mode.
+func (p *Printer) writePage(nodes []Node) error {
+	pg, err := p.BeginPage()
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		if err := pg.writeNode(n.Name(), n.Attributes(), n.Content); err != nil {
+			// leave page mode, so that the next job is not misread
+			pg.Discard()
+			return err
+		}
+	}
+	return pg.End()
+}
+
+// writeNode writes a node of a page element.
+func (pg *PageMode) writeNode(name string, params map[string]string, data string) error {
+	switch name {
+	case "area":
+		var v [4]int
+		for i, a := range []string{"x", "y", "width", "height"} {
+			var err error
+			if v[i], err = atoi(params, a); err != nil {
+				return err
+			}
+		}
+		return pg.Area(v[0], v[1], v[2], v[3])
+
+	case "direction":
+		d, ok := directions[params["dir"]]
+		if !ok {
+			return fmt.Errorf("invalid page direction: %q", params["dir"])
+		}
+		return pg.Direction(d)
+
+	case "position":
+		x, err := atoi(params, "x")
+		if err != nil {
+			return err
+		}
+		y, err := atoi(params, "y")
+		if err != nil {
+			return err
+		}
+		return pg.Position(x, y)
+
+	case "vline-begin", "vline-end":
+		// the line is drawn over the whole print area when it begins, as the
+		// page is laid out by position rather than in lines of text
+		x, style, err := vline(params)
+		if err != nil || name == "vline-end" {
+			return err
+		}
+		return pg.VerticalLine(x, style)
+	}
+
+	return pg.p.WriteNode(name, params, data)
+}
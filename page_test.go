@@ -0,0 +1,145 @@
+package escpos
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPageMode(t *testing.T) {
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+	p.SetAutoCodePage(CP437)
+
+	pg, err := p.BeginPage()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	steps := []func() error{
+		func() error { return pg.Area(0, 0, 400, 300) },
+		func() error { return pg.Direction(DirectionBottomToTop) },
+		func() error { return pg.Text(10, 300, "abc") },
+		func() error { return pg.Print() },
+		func() error { return pg.End() },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	expected := "\x1bL" +
+		"\x1bW\x00\x00\x00\x00\x90\x01\x2c\x01" +
+		"\x1bT\x01" +
+		"\x1b$\x0a\x00\x1d$\x2c\x01abc" +
+		"\x1b\x0c" +
+		"\x0c"
+	if got := string(w.GetWritten()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	if err := pg.Direction(Direction(4)); err == nil {
+		t.Error("Expected error for invalid direction")
+	}
+}
+
+func TestWriteNodesPage(t *testing.T) {
+	nodes, err := getBodyChildren([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <epos-print xmlns="http://www.epson-pos.com/schemas/2011/03/epos-print">
+    </epos-print>
+    <page>
+      <area x="0" y="0" width="200" height="100"/>
+      <direction dir="top_to_bottom"/>
+      <position x="5" y="6"/>
+      <text>hi</text>
+    </page>
+    <feed/>
+  </s:Body>
+</s:Envelope>`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+	p.SetAutoCodePage(CP437)
+	if err := p.WriteNodes(nodes); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "\x1bL" +
+		"\x1bW\x00\x00\x00\x00\xc8\x00\x64\x00" +
+		"\x1bT\x03" +
+		"\x1b$\x05\x00\x1d$\x06\x00" +
+		"hi" +
+		"\x0c" +
+		"\n"
+	if got := string(w.GetWritten()); !strings.HasPrefix(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestWriteNodesPageError(t *testing.T) {
+	nodes, err := getBodyChildren([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <page>
+      <position x="5" y="6"/>
+      <direction dir="sideways"/>
+    </page>
+  </s:Body>
+</s:Envelope>`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+	if err := p.WriteNodes(nodes); err == nil {
+		t.Fatal("Expected error for invalid direction")
+	}
+
+	expected := "\x1bL" + "\x1b$\x05\x00\x1d$\x06\x00" + "\x18\x1bS"
+	if got := string(w.GetWritten()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestWriteNodesPageVerticalLine(t *testing.T) {
+	nodes, err := getBodyChildren([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <page>
+      <area x="0" y="0" width="16" height="3"/>
+      <vline-begin x="4"/>
+      <vline-end x="4"/>
+    </page>
+  </s:Body>
+</s:Envelope>`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+	if err := p.WriteNodes(nodes); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// the line runs over the whole height of the area
+	expected := "\x1bL" +
+		"\x1bW\x00\x00\x00\x00\x10\x00\x03\x00" +
+		"\x1b$\x04\x00\x1d$\x00\x00" +
+		"\x1dv0\x00\x01\x00\x03\x00\x80\x80\x80" +
+		"\x0c"
+	if got := string(w.GetWritten()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	// the print area must be set first
+	pg, _ := p.BeginPage()
+	if err := pg.VerticalLine(4, LineThin); err == nil {
+		t.Error("Expected error without a print area")
+	}
+}
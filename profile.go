@@ -0,0 +1,214 @@
+package escpos
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/morezig/goescpos/raster"
+)
+
+// Feature is a set of printer capabilities.
+type Feature uint32
+
+// Printer capabilities.
+const (
+	// FeatureBitImage is printing raster bit images (GS v 0).
+	FeatureBitImage Feature = 1 << iota
+
+	// FeatureGraphics is printing graphics (GS ( L and GS 8 L).
+	FeatureGraphics
+
+	// FeatureBarcode is printing barcodes with GS k function B. Without
+	// it, the older function A is used for the symbologies it supports.
+	FeatureBarcode
+
+	// FeatureQRCode is printing QR codes (GS ( k). Without it, QR codes
+	// are printed as raster images.
+	FeatureQRCode
+
+	// FeatureSymbols is printing the other 2D symbols (GS ( k).
+	FeatureSymbols
+
+	// FeaturePageMode is page mode (ESC L).
+	FeaturePageMode
+
+	// FeatureCodeTables is selecting character code tables (ESC t).
+	FeatureCodeTables
+
+	// FeatureCutter is an autocutter (GS V).
+	FeatureCutter
+
+	// FeatureDrawer is a cash drawer kick-out connector (ESC p).
+	FeatureDrawer
+
+	// FeatureColumn24 is printing 24-dot column images (ESC *). Without it,
+	// only 8-dot column images are printed.
+	FeatureColumn24
+)
+
+// featureNames are the names of the capabilities.
+var featureNames = []struct {
+	f    Feature
+	name string
+}{
+	{FeatureBitImage, "bit image"},
+	{FeatureGraphics, "graphics"},
+	{FeatureBarcode, "barcode"},
+	{FeatureQRCode, "QR code"},
+	{FeatureSymbols, "2D symbols"},
+	{FeaturePageMode, "page mode"},
+	{FeatureCodeTables, "code tables"},
+	{FeatureCutter, "cutter"},
+	{FeatureDrawer, "drawer"},
+	{FeatureColumn24, "24-dot column images"},
+}
+
+// String satisfies the fmt.Stringer interface.
+func (f Feature) String() string {
+	var names []string
+	for _, n := range featureNames {
+		if f&n.f != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// featuresAll are the capabilities of current full featured printers.
+const featuresAll = FeatureBitImage | FeatureGraphics | FeatureBarcode | FeatureQRCode |
+	FeatureSymbols | FeaturePageMode | FeatureCodeTables | FeatureCutter | FeatureDrawer |
+	FeatureColumn24
+
+// Profile is the capability profile of a printer model.
+type Profile struct {
+	// Name is the model name.
+	Name string
+
+	// Vendor is the manufacturer.
+	Vendor string
+
+	// PaperWidth is the paper width in mm.
+	PaperWidth int
+
+	// Width is the printable width in dots.
+	Width int
+
+	// DPI is the horizontal resolution in dots per inch, or 0 when the
+	// printer does not print at a fixed resolution.
+	DPI int
+
+	// Features are the capabilities of the printer.
+	Features Feature
+
+	// CodePages are the code pages supported by the printer, or nil when it
+	// supports all the known code pages.
+	CodePages []*CodePage
+}
+
+// Supports returns true when the printer supports all of f.
+func (pr *Profile) Supports(f Feature) bool {
+	return pr.Features&f == f
+}
+
+// supportsCodePage returns true when the printer supports cp.
+func (pr *Profile) supportsCodePage(cp *CodePage) bool {
+	if pr.CodePages == nil {
+		return true
+	}
+	for _, c := range pr.CodePages {
+		if c == cp {
+			return true
+		}
+	}
+	return false
+}
+
+// profiles are the known printer profiles, by lower case name.
+var profiles = func() map[string]*Profile {
+	m := make(map[string]*Profile)
+	for _, pr := range []*Profile{
+		{"default", "", 80, defaultMaxWidth, 180, featuresAll, nil},
+		{"simple", "", 80, 576, 203, FeatureBitImage | FeatureBarcode | FeatureCodeTables | FeatureCutter | FeatureDrawer |
+			FeatureColumn24, []*CodePage{CP437, CP850, CP858, CP1252}},
+		{"TM-T88V", "Epson", 80, 512, 180, featuresAll, nil},
+		{"TM-T88VI", "Epson", 80, 512, 180, featuresAll, nil},
+		{"TM-T88IV", "Epson", 80, 512, 180, featuresAll &^ (FeatureQRCode | FeatureSymbols), nil},
+		{"TM-T20II", "Epson", 80, 576, 203, featuresAll, nil},
+		{"TM-T20III", "Epson", 80, 576, 203, featuresAll, nil},
+		{"TM-m30", "Epson", 80, 576, 203, featuresAll, nil},
+		{"TM-m10", "Epson", 58, 420, 203, featuresAll &^ FeatureDrawer, nil},
+		{"TM-P20", "Epson", 58, 384, 203, featuresAll &^ (FeatureCutter | FeatureDrawer), nil},
+		{"TM-P80", "Epson", 80, 576, 203, featuresAll &^ FeatureDrawer, nil},
+		{"TM-U220", "Epson", 76, 200, 0, FeaturePageMode | FeatureCodeTables | FeatureCutter | FeatureDrawer, nil},
+		{"POS-5890", "", 58, 384, 203, FeatureBitImage | FeatureBarcode | FeatureCodeTables | FeatureColumn24,
+			[]*CodePage{CP437, CP850, CP866, CP1252}},
+	} {
+		m[strings.ToLower(pr.Name)] = pr
+	}
+	return m
+}()
+
+// DefaultProfile is the profile of a full featured 80 mm printer.
+var DefaultProfile = profiles["default"]
+
+// LookupProfile returns the profile of the printer model name, as reported by
+// Info, ignoring case.
+func LookupProfile(name string) (*Profile, bool) {
+	pr, ok := profiles[strings.ToLower(strings.TrimSpace(name))]
+	return pr, ok
+}
+
+// ProfileNames returns the names of the known profiles, sorted.
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for _, pr := range profiles {
+		names = append(names, pr.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// supports returns true when the printer supports all of f, as it does when
+// its profile is unknown.
+func (p *Printer) supports(f Feature) bool {
+	return p.profile == nil || p.profile.Supports(f)
+}
+
+// unsupported returns the error for a capability the printer lacks.
+func (p *Printer) unsupported(f Feature) error {
+	return errors.New(p.profile.Name + " does not support " + f.String())
+}
+
+// rasterType returns the printing type to use for t, falling back to the
+// other raster command supported by the printer, or to column images (ESC *),
+// which all printers support, in 8-dot mode when 24-dot columns are not
+// supported.
+func (p *Printer) rasterType(t raster.PrintingType) raster.PrintingType {
+	switch t {
+	case raster.Column24Single, raster.Column24Double:
+		if !p.supports(FeatureColumn24) {
+			return t - raster.Column24Single + raster.Column8Single
+		}
+		return t
+	case raster.BitImage, raster.Graphics:
+	default:
+		return t
+	}
+
+	preferred, other := FeatureBitImage, FeatureGraphics
+	if t == raster.Graphics {
+		preferred, other = other, preferred
+	}
+	switch {
+	case p.supports(preferred):
+		return t
+	case p.supports(other) && t == raster.BitImage:
+		return raster.Graphics
+	case p.supports(other):
+		return raster.BitImage
+	case p.supports(FeatureColumn24):
+		return raster.Column24Double
+	}
+	return raster.Column8Double
+}
@@ -0,0 +1,171 @@
+package escpos
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/morezig/goescpos/raster"
+)
+
+func TestLookupProfile(t *testing.T) {
+	pr, ok := LookupProfile(" tm-t88v ")
+	if !ok || pr.Name != "TM-T88V" {
+		t.Fatalf("Expected TM-T88V profile, got %v", pr)
+	}
+	if _, ok := LookupProfile("TM-X1"); ok {
+		t.Error("Expected unknown profile")
+	}
+	if names := ProfileNames(); len(names) != len(profiles) {
+		t.Errorf("Expected %d names, got %v", len(profiles), names)
+	}
+	if _, err := NewPrinter(NewMockWriter(), WithProfile(nil)); err == nil {
+		t.Error("Expected error for nil profile")
+	}
+}
+
+func TestProfileFallbacks(t *testing.T) {
+	img := []byte{0x80, 0x00}
+
+	testCases := []struct {
+		name     string
+		profile  *Profile
+		print    func(p *Printer) error
+		expected string
+	}{
+		{
+			"Graphics as bit image",
+			&Profile{Name: "test", Features: FeatureBitImage},
+			func(p *Printer) error { return p.Raster(8, 2, 1, img, raster.Graphics) },
+			"\x1dv0\x00\x01\x00\x02\x00\x80\x00",
+		},
+		{
+			"Bit image as column image",
+			&Profile{Name: "test", Features: FeatureColumn24},
+			func(p *Printer) error { return p.Raster(1, 1, 1, img[:1], raster.BitImage) },
+			"\x1b3\x18\x1b*\x21\x01\x00\x80\x00\x00\n\x1b2",
+		},
+		{
+			"Bit image as 8-dot column image",
+			profiles["tm-u220"],
+			func(p *Printer) error { return p.Raster(1, 1, 1, img[:1], raster.BitImage) },
+			"\x1b3\x18\x1b*\x01\x01\x00\x80\n\x1b2",
+		},
+		{
+			"Line as 8-dot column image",
+			profiles["tm-u220"],
+			func(p *Printer) error { return p.HorizontalLine(0, 0, LineThin) },
+			"\x1ba\x00\x1b3\x18\x1b*\x01\x01\x00\x80\n\x1b2\x1ba\x00",
+		},
+		{
+			"Barcode function A",
+			&Profile{Name: "test"},
+			func(p *Printer) error { return p.Barcode(BarcodeCODE39, "AB", &BarcodeOptions{}) },
+			"\x1dk\x04AB\x00",
+		},
+		{
+			"Function B barcode",
+			&Profile{Name: "test"},
+			func(p *Printer) error {
+				if err := p.Barcode(BarcodeCODE128, "{Babc", &BarcodeOptions{}); err == nil {
+					return errors.New("expected error for function B barcode")
+				}
+				return nil
+			},
+			"",
+		},
+		{
+			"No cutter",
+			&Profile{Name: "test"},
+			func(p *Printer) error { return p.Cut() },
+			"",
+		},
+		{
+			"No drawer",
+			&Profile{Name: "test"},
+			func(p *Printer) error { return p.Pulse() },
+			"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewMockWriter()
+			p, _ := NewPrinter(w, WithProfile(tc.profile))
+			if err := tc.print(p); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := string(w.GetWritten()); !strings.HasSuffix(got, tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestProfileQRCodeRaster(t *testing.T) {
+	w := NewMockWriter()
+	pr, _ := LookupProfile("TM-T88IV")
+	p, _ := NewPrinter(w, WithProfile(pr))
+
+	if err := p.Symbol(SymbolQRCode, "hello", nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if written := w.GetWritten(); !bytes.HasPrefix(written, []byte{0x1d, 'v', '0'}) {
+		t.Errorf("Expected raster QR code, got %q", written)
+	}
+	if err := p.Symbol(SymbolPDF417, "hello", nil); err == nil {
+		t.Error("Expected error for unsupported symbol")
+	}
+}
+
+func TestProfileUnsupported(t *testing.T) {
+	pr, _ := LookupProfile("POS-5890")
+	p, _ := NewPrinter(NewMockWriter(), WithProfile(pr))
+
+	if _, err := p.BeginPage(); err == nil {
+		t.Error("Expected error for page mode")
+	}
+	if err := p.SetCodePage(CP852); err == nil {
+		t.Error("Expected error for unsupported code page")
+	}
+	if err := p.SetCodePage(CP866); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	p.SetAutoCodePage(CP852, CP866, CP1251)
+	if len(p.codePages) != 1 || p.codePages[0] != CP866 {
+		t.Errorf("Expected only CP866, got %v", p.codePages)
+	}
+}
+
+func TestProfilePrintableWidth(t *testing.T) {
+	for name, width := range map[string]int{"TM-T88V": 512, "TM-m30": 576} {
+		pr, _ := LookupProfile(name)
+		if w := (PrinterInfo{PaperWidth: pr.PaperWidth}).PrintableWidth(pr.DPI); w != width || w != pr.Width {
+			t.Errorf("Expected %s printable width %d, got %d", name, width, w)
+		}
+	}
+}
+
+func TestProfileWidth(t *testing.T) {
+	pr, _ := LookupProfile("TM-P20")
+	p, _ := NewPrinter(NewMockWriter(), WithProfile(pr))
+	if o := p.imageOptions(nil); o.MaxWidth != 384 {
+		t.Errorf("Expected max width 384, got %d", o.MaxWidth)
+	}
+	if o := p.imageOptions(&ImageOptions{Converter: raster.Converter{MaxWidth: 200}}); o.MaxWidth != 200 {
+		t.Errorf("Expected max width 200, got %d", o.MaxWidth)
+	}
+
+	// text is wrapped to the profile width, unless render options are given
+	if w := p.TextRenderer().Options().Width; w != 384 {
+		t.Errorf("Expected text width 384, got %d", w)
+	}
+	opts := DefaultRenderOptions()
+	opts.Width = 300
+	p, _ = NewPrinter(NewMockWriter(), WithRenderOptions(opts), WithProfile(pr))
+	if w := p.TextRenderer().Options().Width; w != 300 {
+		t.Errorf("Expected text width 300, got %d", w)
+	}
+}
@@ -0,0 +1,174 @@
+package escpos
+
+import (
+	"errors"
+
+	"github.com/morezig/goescpos/raster"
+	"rsc.io/qr"
+)
+
+// QRModel is a QR code model, as used by GS ( k function 165.
+type QRModel byte
+
+// QR code models.
+const (
+	QRModel1     QRModel = 49
+	QRModel2     QRModel = 50
+	QRModelMicro QRModel = 51
+)
+
+// QRErrorCorrection is a QR code error correction level, as used by GS ( k
+// function 169.
+type QRErrorCorrection byte
+
+// QR code error correction levels.
+const (
+	QRErrorCorrectionL QRErrorCorrection = 48 // 7% recovery
+	QRErrorCorrectionM QRErrorCorrection = 49 // 15% recovery
+	QRErrorCorrectionQ QRErrorCorrection = 50 // 25% recovery
+	QRErrorCorrectionH QRErrorCorrection = 51 // 30% recovery
+)
+
+const (
+	// qrMaxData is the maximum number of bytes that can be stored in the
+	// symbol storage area with GS ( k function 180.
+	qrMaxData = 7089
+
+	// qrQuietZone is the width of the quiet zone, in modules, drawn around
+	// QR codes rendered as raster images.
+	qrQuietZone = 4
+
+	// defaultQRModuleSize is the default size of a module, in dots.
+	defaultQRModuleSize = 3
+)
+
+var (
+	// ErrQRCodeEmpty is the empty QR code data error.
+	ErrQRCodeEmpty = errors.New("QR code data is empty")
+
+	// ErrQRCodeTooLong is the QR code data too long error.
+	ErrQRCodeTooLong = errors.New("QR code data too long")
+
+	// ErrQRCodeModuleSize is the invalid QR code module size error.
+	ErrQRCodeModuleSize = errors.New("QR code module size must be between 1 and 16")
+)
+
+// QROptions are the options used when printing a QR code. The zero value
+// prints a model 2 symbol with 3 dot modules and error correction level M.
+type QROptions struct {
+	// Model is the QR code model.
+	Model QRModel
+
+	// ModuleSize is the width and height of a single module, in dots (1-16).
+	ModuleSize int
+
+	// ErrorCorrection is the error correction level.
+	ErrorCorrection QRErrorCorrection
+}
+
+// withDefaults returns a copy of the options with zero values replaced by the
+// defaults.
+func (o *QROptions) withDefaults() QROptions {
+	var opts QROptions
+	if o != nil {
+		opts = *o
+	}
+	if opts.Model == 0 {
+		opts.Model = QRModel2
+	}
+	if opts.ModuleSize == 0 {
+		opts.ModuleSize = defaultQRModuleSize
+	}
+	if opts.ErrorCorrection == 0 {
+		opts.ErrorCorrection = QRErrorCorrectionM
+	}
+	return opts
+}
+
+// validate checks data and the options are valid for a QR code.
+func (o QROptions) validate(data string) error {
+	switch {
+	case len(data) == 0:
+		return ErrQRCodeEmpty
+	case len(data) > qrMaxData:
+		return ErrQRCodeTooLong
+	case o.ModuleSize < 1 || o.ModuleSize > 16:
+		return ErrQRCodeModuleSize
+	}
+	return nil
+}
+
+func (o *QROptions) symbolType() SymbolType {
+	return SymbolQRCode
+}
+
+func (o *QROptions) functions(data string) ([]symbolFunc, error) {
+	opts := o.withDefaults()
+	if err := opts.validate(data); err != nil {
+		return nil, err
+	}
+
+	return []symbolFunc{
+		{65, []byte{byte(opts.Model), 0}},
+		{67, []byte{byte(opts.ModuleSize)}},
+		{69, []byte{byte(opts.ErrorCorrection)}},
+		storeData(data),
+	}, nil
+}
+
+// QRCode prints data as a QR code using the printer's native 2D symbol
+// support (GS ( k).
+func (p *Printer) QRCode(data string, opts *QROptions) error {
+	return p.Symbol(SymbolQRCode, data, opts)
+}
+
+// QRCodeRaster prints data as a QR code rendered to a raster image, for
+// printers that lack native QR code support. Only model 2 symbols can be
+// rendered.
+func (p *Printer) QRCodeRaster(data string, opts *QROptions) error {
+	o := opts.withDefaults()
+	if err := o.validate(data); err != nil {
+		return err
+	}
+	if o.Model != QRModel2 {
+		return errors.New("only model 2 QR codes can be rendered as raster images")
+	}
+
+	level := qr.M
+	switch o.ErrorCorrection {
+	case QRErrorCorrectionL:
+		level = qr.L
+	case QRErrorCorrectionQ:
+		level = qr.Q
+	case QRErrorCorrectionH:
+		level = qr.H
+	}
+
+	code, err := qr.Encode(data, level)
+	if err != nil {
+		return err
+	}
+
+	// pack the modules into a raster, scaled and with a quiet zone
+	width := (code.Size + 2*qrQuietZone) * o.ModuleSize
+	lineWidth := (width + 7) / 8
+	img := make([]byte, lineWidth*width)
+	for y := 0; y < width; y++ {
+		for x := 0; x < width; x++ {
+			if code.Black(x/o.ModuleSize-qrQuietZone, y/o.ModuleSize-qrQuietZone) {
+				img[y*lineWidth+x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+	}
+
+	return p.Raster(width, width, lineWidth, img, raster.BitImage)
+}
+
+// qrLevels are the ePOS-Print error correction levels for QR codes.
+var qrLevels = map[string]QRErrorCorrection{
+	"level_l":       QRErrorCorrectionL,
+	"level_m":       QRErrorCorrectionM,
+	"level_q":       QRErrorCorrectionQ,
+	"level_h":       QRErrorCorrectionH,
+	"level_default": QRErrorCorrectionM,
+}
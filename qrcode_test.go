@@ -0,0 +1,95 @@
+package escpos
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestQRCode(t *testing.T) {
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+
+	err := p.QRCode("hello", &QROptions{
+		ModuleSize:      6,
+		ErrorCorrection: QRErrorCorrectionH,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "" +
+		"\x1d(k\x04\x001A2\x00" + // model 2
+		"\x1d(k\x03\x001C\x06" + // module size
+		"\x1d(k\x03\x001E3" + // error correction H
+		"\x1d(k\x08\x001P0hello" + // store
+		"\x1d(k\x03\x001Q0" // print
+	if got := string(w.GetWritten()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestQRCodeValidation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		opts     *QROptions
+		expected error
+	}{
+		{"Empty", "", nil, ErrQRCodeEmpty},
+		{"Too long", strings.Repeat("a", qrMaxData+1), nil, ErrQRCodeTooLong},
+		{"Module size", "a", &QROptions{ModuleSize: 17}, ErrQRCodeModuleSize},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewMockWriter()
+			p, _ := NewPrinter(w)
+			if err := p.QRCode(tc.data, tc.opts); err != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, err)
+			}
+			if len(w.GetWritten()) != 0 {
+				t.Errorf("Expected nothing written, got %q", w.GetWritten())
+			}
+		})
+	}
+}
+
+func TestQRCodeRaster(t *testing.T) {
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+
+	if err := p.QRCodeRaster("hello", &QROptions{ModuleSize: 2}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// version 1 symbol is 21 modules, plus a 4 module quiet zone each side
+	written := w.GetWritten()
+	header := []byte{0x1d, 0x76, 0x30, 0x00, 8, 0, 58, 0}
+	if !bytes.HasPrefix(written, header) {
+		t.Fatalf("Expected raster header %q, got %q", header, written[:8])
+	}
+	if len(written) != len(header)+8*58 {
+		t.Errorf("Expected %d bytes of raster data, got %d", 8*58, len(written)-len(header))
+	}
+}
+
+func TestWriteNodeSymbol(t *testing.T) {
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+
+	err := p.WriteNode("symbol", map[string]string{
+		"type":  "qrcode_model_2",
+		"level": "level_l",
+		"width": "4",
+	}, "abc")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Contains(w.GetWritten(), []byte("\x1d(k\x03\x001E0")) {
+		t.Errorf("Expected error correction level L, got %q", w.GetWritten())
+	}
+	if !bytes.Contains(w.GetWritten(), []byte("\x1d(k\x06\x001P0abc")) {
+		t.Errorf("Expected symbol data to be stored, got %q", w.GetWritten())
+	}
+}
@@ -1,14 +1,26 @@
 package escpos
 
 import (
+	"errors"
+	"fmt"
 	"log"
+
+	"github.com/morezig/goescpos/raster"
 )
 
 const (
 	gs8lMaxY = 1662
+
+	// defaultBandHeight is the default number of rows sent with each GS v 0
+	// command, small enough for the receive buffer of most printers.
+	defaultBandHeight = 256
+
+	// defaultMaxWidth is the printable width, in dots, used when converting
+	// images to rasters.
+	defaultMaxWidth = 512
 )
 
-//intLowHigh Generate multiple bytes for a number: In lower and higher parts,
+// intLowHigh Generate multiple bytes for a number: In lower and higher parts,
 // or more parts as needed.
 // :param inp_number: Input number// :param out_bytes:
 // The number of bytes to output (1 - 4).
@@ -34,54 +46,207 @@ func intLowHigh(inpNumber int, outBytes int) []byte {
 
 // Raster writes a rasterized version of a black and white image to the printer
 // with the specified width, height, and lineWidth bytes per line.
-func (p *Printer) Raster(width, height, lineWidth int, imgBw []byte, printingType string) {
+func (p *Printer) Raster(width, height, lineWidth int, imgBw []byte, printingType raster.PrintingType) error {
+	switch printingType = p.rasterType(printingType); printingType {
+	case raster.BitImage:
+		return p.bitImage(width, height, lineWidth, imgBw)
+	case raster.Graphics:
+		return p.graphics(width, height, lineWidth, imgBw)
+	case raster.Column8Single, raster.Column8Double, raster.Column24Single, raster.Column24Double:
+		return p.columnImage(width, height, lineWidth, imgBw, printingType)
+	}
+	return fmt.Errorf("unsupported printing type: %s", printingType)
+}
+
+// SetRasterBandHeight sets the number of rows sent with each GS v 0 command
+// when printing bit images. Zero sends whole images in a single command.
+func (p *Printer) SetRasterBandHeight(rows int) error {
+	if rows < 0 {
+		return errors.New("raster band height must not be negative")
+	}
+	p.bandHeight = rows
+	return nil
+}
+
+// SetFlowControl enables waiting for the printer to process each band of a
+// raster image before sending the next. The printer's writer must not be
+// buffered, as the printer is queried in between bands.
+func (p *Printer) SetFlowControl(on bool) {
+	p.flowControl = on
+}
+
+// waitProcessed waits until the printer has processed the data sent so far,
+// by requesting the paper sensor status with GS r, which unlike the real-time
+// status commands is only answered once the preceding data is processed.
+func (p *Printer) waitProcessed() error {
+	// jobs wait for the printer when committed
+	if b, ok := p.w.(*jobBuffer); ok {
+		b.syncs = append(b.syncs, b.Len())
+		return nil
+	}
+
+	ctx := p.currentContext()
+	if err := p.request(ctx, []byte{0x1d, 'r', 1}); err != nil {
+		return err
+	}
+	return p.readFull(ctx, make([]byte, 1))
+}
+
+// bitImage prints the raster with GS v 0, in bands of the raster band height.
+func (p *Printer) bitImage(width, height, lineWidth int, imgBw []byte) error {
+	band := p.bandHeight
+	if band <= 0 || band > height {
+		band = height
+	}
+
+	for y := 0; y < height; y += band {
+		rows := band
+		if rows > height-y {
+			rows = height - y
+		}
 
-	if printingType == "bitImage" {
 		densityByte := byte(0)
 		header := []byte{0x1D, 0x76, 0x30}
 		header = append(header, densityByte)
-		width = (width + 7) >> 3
-		header = append(header, intLowHigh(width, 2)...)
-		header = append(header, intLowHigh(height, 2)...)
+		header = append(header, intLowHigh((width+7)>>3, 2)...)
+		header = append(header, intLowHigh(rows, 2)...)
 
-		fullImage := append(header, imgBw...)
+		fullImage := append(header, imgBw[y*lineWidth:(y+rows)*lineWidth]...)
 
-		p.Write(fullImage)
+		if err := p.write(fullImage); err != nil {
+			return err
+		}
 
-	} else if printingType == "graphics" {
-		for l := 0; l < height; {
-			lines := gs8lMaxY
-			if lines > height-l {
-				lines = height - l
+		if p.flowControl && y+rows < height {
+			if err := p.waitProcessed(); err != nil {
+				return err
 			}
+		}
+	}
+
+	return nil
+}
+
+// Graphics tones, as used by GS 8 L function 112.
+const (
+	toneMonochrome = '0'
+	toneMultiple   = '4'
+)
+
+// graphics stores the raster in the print buffer with GS 8 L and prints it
+// with GS ( L, in chunks of at most gs8lMaxY lines.
+func (p *Printer) graphics(width, height, lineWidth int, imgBw []byte) error {
+	return p.graphicsPlanes(toneMonochrome, ColorFirst, width, height, lineWidth, imgBw)
+}
 
-			f112P := 10 + lines*lineWidth
+// graphicsPlanes stores the planes of a raster, one for each colour from
+// color, in the print buffer with GS 8 L and prints them with GS ( L, in
+// chunks of at most gs8lMaxY lines.
+func (p *Printer) graphicsPlanes(tone byte, color Color, width, height, lineWidth int, planes ...[]byte) error {
+	if !p.supports(FeatureGraphics) {
+		// single black planes fall back to the other raster commands
+		if tone == toneMonochrome && color == ColorFirst && len(planes) == 1 {
+			return p.Raster(width, height, lineWidth, planes[0], raster.Graphics)
+		}
+		return p.unsupported(FeatureGraphics)
+	}
 
-			p.Write([]byte{
+	for l := 0; l < height; {
+		lines := gs8lMaxY
+		if lines > height-l {
+			lines = height - l
+		}
+
+		f112P := 10 + lines*lineWidth
+
+		for i, plane := range planes {
+			err := p.write([]byte{
 				0x1d, 0x38, 0x4c, // GS 8 L, Store the graphics data in the print buffer -- (raster format), p. 252
 				byte(f112P), byte(f112P >> 8), byte(f112P >> 16), byte(f112P >> 24), // p1 p2 p3 p4
-				0x30, 0x70, 0x30, // function 112
+				0x30, 0x70, tone, // function 112
 				0x01, 0x01, // bx, by -- zoom
-				0x31,                          // c -- single-color printing model
-				byte(width), byte(width >> 8), // xl, xh -- number of dots in the horizontal direction
+				graphicsColor(color + Color(i)), // c -- color of the plane
+				byte(width), byte(width >> 8),   // xl, xh -- number of dots in the horizontal direction
 				byte(lines), byte(lines >> 8), // yl, yh -- number of dots in the vertical direction
 			})
+			if err != nil {
+				return err
+			}
 
 			// write line
-			p.Write(imgBw[l*lineWidth : (l+lines)*lineWidth])
-
-			// flush
-			//
-			// GS ( L, Print the graphics data in the print buffer,
-			//   p. 241 Moves print position to the left side of the
-			//   print area after printing of graphics data is
-			//   completed
-			p.Write([]byte{
-				0x1d, 0x28, 0x4c, 0x02, 0x00, 0x30,
-				0x32, //  Fn 50
-			})
+			if err = p.write(plane[l*lineWidth : (l+lines)*lineWidth]); err != nil {
+				return err
+			}
+		}
+
+		// flush
+		//
+		// GS ( L, Print the graphics data in the print buffer,
+		//   p. 241 Moves print position to the left side of the
+		//   print area after printing of graphics data is
+		//   completed
+		err := p.write([]byte{
+			0x1d, 0x28, 0x4c, 0x02, 0x00, 0x30,
+			0x32, //  Fn 50
+		})
+		if err != nil {
+			return err
+		}
+
+		l += lines
+
+		if p.flowControl && l < height {
+			if err := p.waitProcessed(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// columnModes are the ESC * modes and the bytes per column of the column
+// printing types.
+var columnModes = map[raster.PrintingType][2]int{
+	raster.Column8Single:  {0, 1},
+	raster.Column8Double:  {1, 1},
+	raster.Column24Single: {32, 3},
+	raster.Column24Double: {33, 3},
+}
 
-			l += lines
+// columnImage prints the raster in bands of 8 or 24 dots with ESC *, with the
+// line spacing set to the height of a band so that the bands join up.
+func (p *Printer) columnImage(width, height, lineWidth int, imgBw []byte, printingType raster.PrintingType) error {
+	mode := columnModes[printingType]
+	m, n := byte(mode[0]), mode[1]
+
+	// ESC 3, 24 motion units (the height of a band in either mode)
+	if err := p.write([]byte{0x1b, 0x33, 24}); err != nil {
+		return err
+	}
+
+	for y := 0; y < height; y += 8 * n {
+		band := []byte{0x1b, 0x2a, m}
+		band = append(band, intLowHigh(width, 2)...)
+		for x := 0; x < width; x++ {
+			for b := 0; b < n; b++ {
+				var c byte
+				for i := 0; i < 8; i++ {
+					row := y + b*8 + i
+					if row < height && imgBw[row*lineWidth+x/8]&(0x80>>uint(x%8)) != 0 {
+						c |= 0x80 >> uint(i)
+					}
+				}
+				band = append(band, c)
+			}
+		}
+		band = append(band, '\n')
+
+		if err := p.write(band); err != nil {
+			return err
 		}
 	}
+
+	// ESC 2, default line spacing
+	return p.write([]byte{0x1b, 0x32})
 }
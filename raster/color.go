@@ -0,0 +1,54 @@
+package raster
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// SplitColors splits img into the planes printed in black and in red on two
+// colour printers. Pixels with a saturated red hue go to the red plane, the
+// others to the black plane. Like the rasters of a Converter, the planes are
+// light where ink is printed: the pixels of the other plane, and white
+// pixels, are black in either plane.
+func SplitColors(img image.Image) (black, red *image.Gray) {
+	b := img.Bounds()
+	black = image.NewGray(b)
+	red = image.NewGray(b)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.At(x, y)
+			if s, ok := redness(c); ok {
+				red.SetGray(x, y, color.Gray{uint8(0xff * s)})
+			} else {
+				g := color.GrayModel.Convert(c).(color.Gray)
+				black.SetGray(x, y, color.Gray{0xff - g.Y})
+			}
+		}
+	}
+
+	return black, red
+}
+
+// redness returns the saturation of c, and true when c is a saturated red:
+// a hue within 30° of red and a saturation of at least 0.4.
+func redness(c color.Color) (float64, bool) {
+	r, g, b, _ := c.RGBA()
+	fr, fg, fb := float64(r)/0xffff, float64(g)/0xffff, float64(b)/0xffff
+
+	max := math.Max(fr, math.Max(fg, fb))
+	min := math.Min(fr, math.Min(fg, fb))
+	if max == 0 || max != fr {
+		return 0, false
+	}
+
+	s := (max - min) / max
+	if s < 0.4 {
+		return s, false
+	}
+
+	// hue in degrees, from -60 to 60 when red is the largest component
+	hue := 60 * (fg - fb) / (max - min)
+	return s, math.Abs(hue) <= 30
+}
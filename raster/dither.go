@@ -0,0 +1,261 @@
+package raster
+
+import (
+	"fmt"
+	"math"
+)
+
+// Dither is the algorithm used to reduce an image to black and white dots.
+type Dither int
+
+// Dithering algorithms.
+const (
+	// DitherNone compares every pixel to the threshold.
+	DitherNone Dither = iota
+
+	// DitherFloydSteinberg diffuses the error with the Floyd–Steinberg
+	// kernel.
+	DitherFloydSteinberg
+
+	// DitherAtkinson diffuses three quarters of the error with the Atkinson
+	// kernel, which keeps more contrast.
+	DitherAtkinson
+
+	// DitherStucki diffuses the error with the Stucki kernel.
+	DitherStucki
+
+	// DitherBayer4 is ordered dithering with a 4x4 Bayer matrix.
+	DitherBayer4
+
+	// DitherBayer8 is ordered dithering with an 8x8 Bayer matrix.
+	DitherBayer8
+)
+
+// ditherNames are the names of the dithering algorithms.
+var ditherNames = map[Dither]string{
+	DitherNone:           "none",
+	DitherFloydSteinberg: "floyd-steinberg",
+	DitherAtkinson:       "atkinson",
+	DitherStucki:         "stucki",
+	DitherBayer4:         "bayer4",
+	DitherBayer8:         "bayer8",
+}
+
+// String satisfies the fmt.Stringer interface.
+func (d Dither) String() string {
+	if s, ok := ditherNames[d]; ok {
+		return s
+	}
+	return fmt.Sprintf("Dither(%d)", int(d))
+}
+
+// ParseDither returns the dithering algorithm with the name s.
+func ParseDither(s string) (Dither, error) {
+	for d, name := range ditherNames {
+		if name == s {
+			return d, nil
+		}
+	}
+	return DitherNone, fmt.Errorf("raster: unknown dither %q", s)
+}
+
+// diffusion is the share of the quantisation error passed to the pixel at
+// an offset from the current one.
+type diffusion struct {
+	dx, dy int
+	weight float64
+}
+
+// kernels are the error diffusion kernels.
+var kernels = map[Dither][]diffusion{
+	DitherFloydSteinberg: {
+		{1, 0, 7.0 / 16},
+		{-1, 1, 3.0 / 16}, {0, 1, 5.0 / 16}, {1, 1, 1.0 / 16},
+	},
+	DitherAtkinson: {
+		{1, 0, 1.0 / 8}, {2, 0, 1.0 / 8},
+		{-1, 1, 1.0 / 8}, {0, 1, 1.0 / 8}, {1, 1, 1.0 / 8},
+		{0, 2, 1.0 / 8},
+	},
+	DitherStucki: {
+		{1, 0, 8.0 / 42}, {2, 0, 4.0 / 42},
+		{-2, 1, 2.0 / 42}, {-1, 1, 4.0 / 42}, {0, 1, 8.0 / 42}, {1, 1, 4.0 / 42}, {2, 1, 2.0 / 42},
+		{-2, 2, 1.0 / 42}, {-1, 2, 2.0 / 42}, {0, 2, 4.0 / 42}, {1, 2, 2.0 / 42}, {2, 2, 1.0 / 42},
+	},
+}
+
+// bayerMatrix returns the n×n Bayer index matrix, n being a power of two.
+func bayerMatrix(n int) [][]int {
+	m := [][]int{{0}}
+	for size := 1; size < n; size *= 2 {
+		next := make([][]int, size*2)
+		for y := range next {
+			next[y] = make([]int, size*2)
+			for x := range next[y] {
+				v := 4 * m[y%size][x%size]
+				switch {
+				case x >= size && y < size:
+					v += 2
+				case x < size && y >= size:
+					v += 3
+				case x >= size && y >= size:
+					v++
+				}
+				next[y][x] = v
+			}
+		}
+		m = next
+	}
+	return m
+}
+
+var (
+	bayer4 = bayerMatrix(4)
+	bayer8 = bayerMatrix(8)
+)
+
+// adjust applies the gamma, contrast and brightness adjustments of the
+// converter to the lightness v.
+func (c *Converter) adjust(v float64) float64 {
+	if c.Gamma > 0 && c.Gamma != 1 {
+		v = math.Pow(v, 1/c.Gamma)
+	}
+	v = (v-0.5)*(1+c.Contrast) + 0.5 + c.Brightness
+	return math.Max(0, math.Min(1, v))
+}
+
+// otsu returns the threshold between the two classes of lightness values
+// that maximises the variance between them (Otsu's method).
+func otsu(values []float64) float64 {
+	var hist [256]int
+	for _, v := range values {
+		hist[int(v*255+0.5)]++
+	}
+
+	var sum float64
+	for i, n := range hist {
+		sum += float64(i * n)
+	}
+
+	var sumB, best float64
+	var wB int
+	threshold := 128
+	for i, n := range hist {
+		wB += n
+		if wB == 0 {
+			continue
+		}
+		wF := len(values) - wB
+		if wF == 0 {
+			break
+		}
+		sumB += float64(i * n)
+		mB := sumB / float64(wB)
+		mF := (sum - sumB) / float64(wF)
+		if between := float64(wB) * float64(wF) * (mB - mF) * (mB - mF); between > best {
+			best, threshold = between, i+1
+		}
+	}
+
+	return (float64(threshold) - 0.5) / 255
+}
+
+// quantise reduces the width×height lightness values to black and white
+// with the dithering algorithm of the converter, returning true for light
+// dots.
+func (c *Converter) quantise(values []float64, width, height int, threshold float64) []bool {
+	out := make([]bool, len(values))
+
+	switch c.Dither {
+	case DitherBayer4, DitherBayer8:
+		m := bayer4
+		if c.Dither == DitherBayer8 {
+			m = bayer8
+		}
+		n := len(m)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				t := (float64(m[y%n][x%n])+0.5)/float64(n*n) + threshold - 0.5
+				out[y*width+x] = values[y*width+x] >= t
+			}
+		}
+
+	case DitherFloydSteinberg, DitherAtkinson, DitherStucki:
+		kernel := kernels[c.Dither]
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				v := values[y*width+x]
+				q := 0.0
+				if v >= threshold {
+					q = 1
+					out[y*width+x] = true
+				}
+				e := v - q
+				for _, d := range kernel {
+					nx, ny := x+d.dx, y+d.dy
+					if nx >= 0 && nx < width && ny < height {
+						values[ny*width+nx] += e * d.weight
+					}
+				}
+			}
+		}
+
+	default:
+		for i, v := range values {
+			out[i] = v >= threshold
+		}
+	}
+
+	return out
+}
+
+// quantiseLevels reduces the width×height lightness values to levels tones
+// with the dithering algorithm of the converter, returning the tone of each
+// dot from 0 (darkest) to levels-1 (lightest).
+func (c *Converter) quantiseLevels(values []float64, width, height, levels int) []byte {
+	out := make([]byte, len(values))
+	max := float64(levels - 1)
+
+	level := func(v float64) int {
+		return int(math.Max(0, math.Min(max, v)))
+	}
+
+	switch c.Dither {
+	case DitherBayer4, DitherBayer8:
+		m := bayer4
+		if c.Dither == DitherBayer8 {
+			m = bayer8
+		}
+		n := len(m)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				t := (float64(m[y%n][x%n]) + 0.5) / float64(n*n)
+				out[y*width+x] = byte(level(values[y*width+x]*max + t))
+			}
+		}
+
+	case DitherFloydSteinberg, DitherAtkinson, DitherStucki:
+		kernel := kernels[c.Dither]
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				v := values[y*width+x]
+				l := level(v*max + 0.5)
+				out[y*width+x] = byte(l)
+				e := v - float64(l)/max
+				for _, d := range kernel {
+					nx, ny := x+d.dx, y+d.dy
+					if nx >= 0 && nx < width && ny < height {
+						values[ny*width+nx] += e * d.weight
+					}
+				}
+			}
+		}
+
+	default:
+		for i, v := range values {
+			out[i] = byte(level(v*max + 0.5))
+		}
+	}
+
+	return out
+}
@@ -1,12 +1,61 @@
 package raster
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 )
 
+// PrintingType is the command used to print a raster.
+type PrintingType int
+
+// Printing types.
+const (
+	// BitImage prints the raster with GS v 0.
+	BitImage PrintingType = iota
+
+	// Graphics stores the raster with GS 8 L and prints it with GS ( L.
+	Graphics
+
+	// Column8Single, Column8Double, Column24Single and Column24Double print
+	// the raster in bands of 8 or 24 dots with ESC *, in single or double
+	// horizontal density. Single density prints every column two dots wide.
+	Column8Single
+	Column8Double
+	Column24Single
+	Column24Double
+)
+
+// printingTypeNames are the names of the printing types.
+var printingTypeNames = map[PrintingType]string{
+	BitImage:       "bitImage",
+	Graphics:       "graphics",
+	Column8Single:  "column8Single",
+	Column8Double:  "column8Double",
+	Column24Single: "column24Single",
+	Column24Double: "column24Double",
+}
+
+// String satisfies the fmt.Stringer interface.
+func (t PrintingType) String() string {
+	if s, ok := printingTypeNames[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("PrintingType(%d)", int(t))
+}
+
+// ParsePrintingType returns the printing type with the name s.
+func ParsePrintingType(s string) (PrintingType, error) {
+	for t, name := range printingTypeNames {
+		if name == s {
+			return t, nil
+		}
+	}
+	return BitImage, fmt.Errorf("raster: unknown printing type %q", s)
+}
+
 type Target interface {
-	Raster(width, height, bytesWidth int, rasterData []byte, printingType string)
+	Raster(width, height, bytesWidth int, rasterData []byte, printingType PrintingType) error
 }
 
 type Converter struct {
@@ -15,55 +64,160 @@ type Converter struct {
 
 	// The threashold between white and black dots
 	Threshold float64
-}
 
-func (c *Converter) Print(img image.Image, target Target, imgtype string) {
-	sz := img.Bounds().Size()
+	// AutoThreshold computes the threshold from the image with Otsu's
+	// method, ignoring Threshold
+	AutoThreshold bool
+
+	// The dithering algorithm, DitherNone for a plain threshold
+	Dither Dither
+
+	// Adjustments applied before dithering: Gamma is a gamma correction
+	// (zero or one for none), Contrast and Brightness range from -1 to 1
+	// (zero for none)
+	Gamma, Contrast, Brightness float64
+
+	// AutoCrop removes the margins of the image that print no dots
+	AutoCrop bool
+
+	// The clockwise rotation of the image, applied after cropping
+	Rotation Rotation
+
+	// The target width of the image, in dots or in millimetres at
+	// DotsPerMM (8 when zero), or an aspect preserving Scale factor. The
+	// image is not scaled when all are zero
+	Width     int
+	WidthMM   float64
+	DotsPerMM float64
+	Scale     float64
 
-	data, rw, bw := c.ToRaster(img)
+	// How the image is fitted to the printable width, FitShrink by default
+	Fit FitMode
 
-	// target.Raster(rw, sz.Y, bw, data, "bitImage")
-	target.Raster(rw, sz.Y, bw, data, imgtype)
+	// Unprinted dots added around the image
+	Padding int
 
+	// The horizontal position of the image within MaxWidth
+	Placement Placement
+}
+
+func (c *Converter) Print(img image.Image, target Target, imgtype PrintingType) error {
+	data, rw, h, bw := c.raster(img)
+
+	// target.Raster(rw, h, bw, data, BitImage)
+	return target.Raster(rw, h, bw, data, imgtype)
 }
 
 func (c *Converter) ToRaster(img image.Image) (data []byte, imageWidth, bytesWidth int) {
-	sz := img.Bounds().Size()
+	data, imageWidth, _, bytesWidth = c.raster(img)
+	return
+}
 
-	// lines are packed in bits
-	imageWidth = sz.X
-	if imageWidth > c.MaxWidth {
+// values returns the adjusted lightness of the dots of img, once
+// transformed, and its size.
+func (c *Converter) values(img image.Image) (values []float64, w, h int) {
+	return c.thresholdValues(img, c.threshold(img))
+}
+
+// thresholdValues is like values, cropping img with threshold.
+func (c *Converter) thresholdValues(img image.Image, threshold float64) (values []float64, w, h int) {
+	img = c.transform(img, threshold)
+	b := img.Bounds()
+	w, h = b.Dx(), b.Dy()
+
+	values = make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			values[y*w+x] = c.adjust(lightness(img.At(b.Min.X+x, b.Min.Y+y)))
+		}
+	}
+	return values, w, h
+}
+
+// layout returns the size of the raster of a w×h image, with its padding
+// and placement, and the position of the image in it.
+func (c *Converter) layout(w, h int) (x, y, width, height int) {
+	x, width = c.place(w + 2*c.Padding)
+	if width > c.MaxWidth {
 		// truncate if image is too large
-		imageWidth = c.MaxWidth
+		width = c.MaxWidth
 	}
+	return x + c.Padding, c.Padding, width, h + 2*c.Padding
+}
+
+// raster converts img to a raster of imageWidth×height dots, packed in lines
+// of bytesWidth bytes.
+func (c *Converter) raster(img image.Image) (data []byte, imageWidth, height, bytesWidth int) {
+	// the same threshold decides which dots are cropped and printed
+	threshold := c.threshold(img)
+	values, w, h := c.thresholdValues(img, threshold)
+	dots := c.quantise(values, w, h, threshold)
+
+	// padding and placement
+	offsetX, offsetY, imageWidth, height := c.layout(w, h)
 
+	// lines are packed in bits
 	bytesWidth = imageWidth / 8
 	if imageWidth%8 != 0 {
 		bytesWidth += 1
 	}
 
-	data = make([]byte, bytesWidth*sz.Y)
+	data = make([]byte, bytesWidth*height)
 
-	for y := 0; y < sz.Y; y++ {
-		for x := 0; x < imageWidth; x++ {
-			if lightness(img.At(x, y)) >= c.Threshold {
+	for y := 0; y < h; y++ {
+		for x := 0; x < w && offsetX+x < imageWidth; x++ {
+			if dots[y*w+x] {
 				// position in data is: line_start + x / 8
 				// line_start is y * bytesWidth
 				// then 8 bits per byte
-				data[y*bytesWidth+x/8] |= 0x80 >> uint(x%8)
+				px, py := offsetX+x, offsetY+y
+				data[py*bytesWidth+px/8] |= 0x80 >> uint(px%8)
 			}
+		}
+	}
 
-			// if chk_lightness(img.At(x, y)) {
-			// 	data[y*bytesWidth+x/8] |= 1 << uint(x%8)
-			// } else {
-			// 	data[y*bytesWidth+x/8] &^= 1 << uint(x%8)
-			// }
+	return
+}
+
+// ToTones converts img to a raster of width×height dots of levels tones
+// (at least 2), one byte per dot, from 0 for the darkest to levels-1 for the
+// lightest. The threshold is not used.
+func (c *Converter) ToTones(img image.Image, levels int) (data []byte, width, height int) {
+	if levels < 2 {
+		levels = 2
+	}
+
+	values, w, h := c.values(img)
+	tones := c.quantiseLevels(values, w, h, levels)
+
+	offsetX, offsetY, width, height := c.layout(w, h)
+	data = make([]byte, width*height)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w && offsetX+x < width; x++ {
+			data[(offsetY+y)*width+offsetX+x] = tones[y*w+x]
 		}
 	}
 
 	return
 }
 
+// threshold returns the threshold used for img: the converter threshold, or
+// the one computed from the lightness of img with AutoThreshold.
+func (c *Converter) threshold(img image.Image) float64 {
+	if !c.AutoThreshold {
+		return c.Threshold
+	}
+
+	b := img.Bounds()
+	values := make([]float64, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			values = append(values, c.adjust(lightness(img.At(x, y))))
+		}
+	}
+	return otsu(values)
+}
+
 const (
 	lumR, lumG, lumB = 55, 182, 18
 )
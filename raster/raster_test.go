@@ -0,0 +1,248 @@
+package raster
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+// gradient returns a width×height image that is black on the left and
+// white on the right.
+func gradient(width, height int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetGray(x, y, color.Gray{uint8(x * 255 / (width - 1))})
+		}
+	}
+	return img
+}
+
+func TestBayerMatrix(t *testing.T) {
+	expected := [][]int{
+		{0, 8, 2, 10},
+		{12, 4, 14, 6},
+		{3, 11, 1, 9},
+		{15, 7, 13, 5},
+	}
+	if got := bayerMatrix(4); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestConverterDither(t *testing.T) {
+	img := gradient(64, 16)
+
+	for _, d := range []Dither{DitherNone, DitherFloydSteinberg, DitherAtkinson, DitherStucki, DitherBayer4, DitherBayer8} {
+		t.Run(d.String(), func(t *testing.T) {
+			c := &Converter{MaxWidth: 64, Threshold: 0.5, Dither: d}
+			data, width, bytesWidth := c.ToRaster(img)
+			if width != 64 || bytesWidth != 8 || len(data) != 8*16 {
+				t.Fatalf("Unexpected size %d, %d, %d", width, bytesWidth, len(data))
+			}
+
+			// the dark quarter is mostly clear and the light quarter mostly
+			// set, with a mix in between
+			count := func(from, to int) int {
+				n := 0
+				for y := 0; y < 16; y++ {
+					for x := from; x < to; x++ {
+						if data[y*bytesWidth+x/8]&(0x80>>uint(x%8)) != 0 {
+							n++
+						}
+					}
+				}
+				return n
+			}
+			dark, light := count(0, 16), count(48, 64)
+			if dark > 16*16/4 || light < 16*16*3/4 {
+				t.Errorf("Unexpected dots: %d dark, %d light", dark, light)
+			}
+			if d != DitherNone {
+				if mid := count(24, 40); mid < 16*16/4 || mid > 16*16*3/4 {
+					t.Errorf("Expected mixed dots in the middle, got %d", mid)
+				}
+			}
+		})
+	}
+}
+
+func TestConverterAdjust(t *testing.T) {
+	testCases := []struct {
+		name    string
+		c       Converter
+		in, out float64
+	}{
+		{"None", Converter{}, 0.25, 0.25},
+		{"Gamma", Converter{Gamma: 2}, 0.25, 0.5},
+		{"Contrast", Converter{Contrast: 1}, 0.25, 0},
+		{"Brightness", Converter{Brightness: 0.5}, 0.25, 0.75},
+		{"Clamped", Converter{Brightness: 1}, 0.25, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.c.adjust(tc.in); got != tc.out {
+				t.Errorf("Expected %v, got %v", tc.out, got)
+			}
+		})
+	}
+}
+
+func TestOtsu(t *testing.T) {
+	values := []float64{0.1, 0.1, 0.2, 0.2, 0.7, 0.8, 0.8}
+	if got := otsu(values); got <= 0.2 || got > 0.7 {
+		t.Errorf("Expected threshold between the classes, got %v", got)
+	}
+
+	c := &Converter{MaxWidth: 8, Threshold: 0.9, AutoThreshold: true}
+	data, _, _ := c.ToRaster(gradient(8, 1))
+	if data[0] != 0x0f {
+		t.Errorf("Expected %08b, got %08b", 0x0f, data[0])
+	}
+}
+
+func TestParseDither(t *testing.T) {
+	d, err := ParseDither("atkinson")
+	if err != nil || d != DitherAtkinson {
+		t.Errorf("Expected atkinson, got %v, %v", d, err)
+	}
+	if _, err := ParseDither("bogus"); err == nil {
+		t.Error("Expected error for unknown dither")
+	}
+}
+
+func TestConverterTargetWidth(t *testing.T) {
+	testCases := []struct {
+		name     string
+		c        Converter
+		w        int
+		expected int
+	}{
+		{"Unscaled", Converter{MaxWidth: 512}, 100, 100},
+		{"Shrink", Converter{MaxWidth: 512}, 600, 512},
+		{"Truncate", Converter{MaxWidth: 512, Fit: FitNone}, 600, 600},
+		{"Fit width", Converter{MaxWidth: 512, Fit: FitWidth}, 100, 512},
+		{"Dots", Converter{MaxWidth: 512, Width: 200}, 100, 200},
+		{"Millimetres", Converter{MaxWidth: 512, WidthMM: 40}, 100, 320},
+		{"Millimetres 180 dpi", Converter{MaxWidth: 512, WidthMM: 40, DotsPerMM: 7}, 100, 280},
+		{"Scale", Converter{MaxWidth: 512, Scale: 1.5}, 100, 150},
+		{"Padding", Converter{MaxWidth: 512, Padding: 6}, 600, 500},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.c.targetWidth(tc.w); got != tc.expected {
+				t.Errorf("Expected %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRotate(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 3, 2))
+	img.SetGray(0, 0, color.Gray{0xff})
+
+	testCases := []struct {
+		r    Rotation
+		w, h int
+		x, y int
+	}{
+		{Rotate90, 2, 3, 1, 0},
+		{Rotate180, 3, 2, 2, 1},
+		{Rotate270, 2, 3, 0, 2},
+	}
+
+	for _, tc := range testCases {
+		got := rotate(img, tc.r)
+		if sz := got.Bounds().Size(); sz.X != tc.w || sz.Y != tc.h {
+			t.Errorf("%d: expected %dx%d, got %v", tc.r, tc.w, tc.h, sz)
+		}
+		if lightness(got.At(tc.x, tc.y)) != 1 {
+			t.Errorf("%d: expected corner at %d,%d", tc.r, tc.x, tc.y)
+		}
+	}
+}
+
+func TestConverterLayout(t *testing.T) {
+	// a 4x2 image with a single light dot surrounded by dark margins
+	img := image.NewGray(image.Rect(0, 0, 4, 2))
+	img.SetGray(1, 1, color.Gray{0xff})
+
+	testCases := []struct {
+		name     string
+		c        Converter
+		width    int
+		expected []byte
+	}{
+		{"Plain", Converter{MaxWidth: 16}, 4, []byte{0x00, 0x40}},
+		{"Crop", Converter{MaxWidth: 16, AutoCrop: true}, 1, []byte{0x80}},
+		{"Padding", Converter{MaxWidth: 16, AutoCrop: true, Padding: 1}, 3, []byte{0x00, 0x40, 0x00}},
+		{"Center", Converter{MaxWidth: 16, AutoCrop: true, Placement: PlaceCenter}, 16, []byte{0x01, 0x00}},
+		{"Right", Converter{MaxWidth: 16, AutoCrop: true, Placement: PlaceRight}, 16, []byte{0x00, 0x01}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := tc.c
+			c.Threshold = 0.5
+			data, width, _ := c.ToRaster(img)
+			if width != tc.width || !reflect.DeepEqual(data, tc.expected) {
+				t.Errorf("Expected %d %x, got %d %x", tc.width, tc.expected, width, data)
+			}
+		})
+	}
+}
+
+func TestConverterCropAutoThreshold(t *testing.T) {
+	// a low contrast block, with no dot at the fixed threshold
+	img := image.NewGray(image.Rect(0, 0, 16, 4))
+	for i := range img.Pix {
+		img.Pix[i] = 0x40
+	}
+	for y := 1; y < 3; y++ {
+		for x := 4; x < 12; x++ {
+			img.SetGray(x, y, color.Gray{Y: 0x70})
+		}
+	}
+
+	c := &Converter{MaxWidth: 16, Threshold: 0.9, AutoThreshold: true, AutoCrop: true}
+	data, width, _ := c.ToRaster(img)
+	if expected := []byte{0xff, 0xff}; width != 8 || !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected 8 %x, got %d %x", expected, width, data)
+	}
+}
+
+func TestConverterToTones(t *testing.T) {
+	data, width, height := (&Converter{MaxWidth: 64, Placement: PlaceLeft}).ToTones(gradient(32, 1), 4)
+	if width != 64 || height != 1 {
+		t.Fatalf("Unexpected size %dx%d", width, height)
+	}
+	if data[0] != 0 || data[31] != 3 || data[12] != 1 || data[20] != 2 || data[40] != 0 {
+		t.Errorf("Unexpected tones %v", data)
+	}
+}
+
+func TestSplitColors(t *testing.T) {
+	testCases := []struct {
+		name string
+		c    color.Color
+		red  bool
+	}{
+		{"Red", color.RGBA{0xff, 0x00, 0x00, 0xff}, true},
+		{"Dark red", color.RGBA{0x80, 0x10, 0x00, 0xff}, true},
+		{"Pink", color.RGBA{0xff, 0xd0, 0xd0, 0xff}, false},
+		{"Orange", color.RGBA{0xff, 0xa0, 0x00, 0xff}, false},
+		{"Black", color.RGBA{0x00, 0x00, 0x00, 0xff}, false},
+		{"White", color.RGBA{0xff, 0xff, 0xff, 0xff}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, red := redness(tc.c); red != tc.red {
+				t.Errorf("Expected %t, got %t", tc.red, red)
+			}
+		})
+	}
+}
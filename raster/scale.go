@@ -0,0 +1,175 @@
+package raster
+
+import (
+	"image"
+	"math"
+
+	"github.com/nfnt/resize"
+)
+
+// FitMode is how an image is scaled to the printable width.
+type FitMode int
+
+// Fit modes.
+const (
+	// FitShrink scales down images wider than the printable width.
+	FitShrink FitMode = iota
+
+	// FitNone truncates images wider than the printable width.
+	FitNone
+
+	// FitWidth scales images up or down to the printable width.
+	FitWidth
+)
+
+// Rotation is a clockwise rotation of an image, in degrees.
+type Rotation int
+
+// Rotations.
+const (
+	Rotate0   Rotation = 0
+	Rotate90  Rotation = 90
+	Rotate180 Rotation = 180
+	Rotate270 Rotation = 270
+)
+
+// Placement is the horizontal position of an image within the printable
+// width.
+type Placement int
+
+// Placements.
+const (
+	// PlaceNone leaves the raster as wide as the image.
+	PlaceNone Placement = iota
+	PlaceLeft
+	PlaceCenter
+	PlaceRight
+)
+
+// defaultDotsPerMM is the resolution of most receipt printers (203 dpi).
+const defaultDotsPerMM = 8
+
+// availableWidth returns the width in dots available to the image, inside
+// the padding.
+func (c *Converter) availableWidth() int {
+	return c.MaxWidth - 2*c.Padding
+}
+
+// targetWidth returns the width in dots the image w dots wide is scaled
+// to, or w when it is not scaled.
+func (c *Converter) targetWidth(w int) int {
+	target := w
+	switch {
+	case c.WidthMM > 0:
+		dpmm := c.DotsPerMM
+		if dpmm <= 0 {
+			dpmm = defaultDotsPerMM
+		}
+		target = int(math.Round(c.WidthMM * dpmm))
+	case c.Width > 0:
+		target = c.Width
+	case c.Scale > 0:
+		target = int(math.Round(float64(w) * c.Scale))
+	}
+
+	avail := c.availableWidth()
+	switch {
+	case c.Fit == FitWidth && avail > 0:
+		target = avail
+	case c.Fit == FitShrink && avail > 0 && target > avail:
+		target = avail
+	}
+	return target
+}
+
+// transform crops, rotates and scales img as set in the converter, cropping
+// the margins with no dots at threshold.
+func (c *Converter) transform(img image.Image, threshold float64) image.Image {
+	if c.AutoCrop {
+		img = c.crop(img, threshold)
+	}
+	img = rotate(img, c.Rotation)
+
+	w := img.Bounds().Dx()
+	if target := c.targetWidth(w); target != w && target > 0 {
+		img = resize.Resize(uint(target), 0, img, resize.Lanczos3)
+	}
+	return img
+}
+
+// crop returns img without the margins that print no dots, those darker
+// than threshold.
+func (c *Converter) crop(img image.Image, threshold float64) image.Image {
+	b := img.Bounds()
+	r := image.Rectangle{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if c.adjust(lightness(img.At(x, y))) >= threshold {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if r.Empty() {
+		return img
+	}
+
+	if s, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dst.Set(x-r.Min.X, y-r.Min.Y, img.At(x, y))
+		}
+	}
+	return dst
+}
+
+// rotate returns img rotated clockwise by r.
+func rotate(img image.Image, r Rotation) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	var dst *image.RGBA
+	switch r {
+	case Rotate90, Rotate270:
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	case Rotate180:
+		dst = image.NewRGBA(image.Rect(0, 0, w, h))
+	default:
+		return img
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			px := img.At(b.Min.X+x, b.Min.Y+y)
+			switch r {
+			case Rotate90:
+				dst.Set(h-1-y, x, px)
+			case Rotate180:
+				dst.Set(w-1-x, h-1-y, px)
+			case Rotate270:
+				dst.Set(y, w-1-x, px)
+			}
+		}
+	}
+	return dst
+}
+
+// place returns the offset of a raster w dots wide within the printable
+// width, and the width of the placed raster.
+func (c *Converter) place(w int) (offset, width int) {
+	if c.Placement == PlaceNone || w >= c.MaxWidth {
+		return 0, w
+	}
+
+	switch c.Placement {
+	case PlaceCenter:
+		offset = (c.MaxWidth - w) / 2
+	case PlaceRight:
+		offset = c.MaxWidth - w
+	}
+	return offset, c.MaxWidth
+}
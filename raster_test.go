@@ -0,0 +1,102 @@
+package escpos
+
+import (
+	"testing"
+
+	"github.com/morezig/goescpos/raster"
+)
+
+func TestRaster(t *testing.T) {
+	// a 10x9 raster with the top-left and bottom-right dots set
+	img := make([]byte, 2*9)
+	img[0] = 0x80
+	img[8*2+1] = 0x40
+
+	testCases := []struct {
+		name     string
+		typ      raster.PrintingType
+		expected string
+	}{
+		{
+			"Bit image", raster.BitImage,
+			"\x1dv0\x00\x02\x00\x09\x00" + string(img),
+		},
+		{
+			"Column 8 double", raster.Column8Double,
+			"\x1b3\x18" +
+				"\x1b*\x01\x0a\x00\x80\x00\x00\x00\x00\x00\x00\x00\x00\x00\n" +
+				"\x1b*\x01\x0a\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x80\n" +
+				"\x1b2",
+		},
+		{
+			"Column 24 single", raster.Column24Single,
+			"\x1b3\x18" +
+				"\x1b*\x20\x0a\x00\x80\x00\x00" + "\x00\x00\x00" + "\x00\x00\x00" + "\x00\x00\x00" + "\x00\x00\x00" +
+				"\x00\x00\x00" + "\x00\x00\x00" + "\x00\x00\x00" + "\x00\x00\x00" + "\x00\x80\x00\n" +
+				"\x1b2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewMockWriter()
+			p, _ := NewPrinter(w)
+			if err := p.Raster(10, 9, 2, img, tc.typ); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := string(w.GetWritten()); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+
+	p, _ := NewPrinter(NewMockWriter())
+	if err := p.Raster(10, 9, 2, img, raster.PrintingType(42)); err == nil {
+		t.Error("Expected error for unsupported printing type")
+	}
+}
+
+func TestRasterBanding(t *testing.T) {
+	img := []byte{1, 2, 3, 4, 5}
+
+	w := NewMockWriter()
+	p, _ := NewPrinter(w, WithRasterBandHeight(2))
+	if err := p.Raster(8, 5, 1, img, raster.BitImage); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "" +
+		"\x1dv0\x00\x01\x00\x02\x00\x01\x02" +
+		"\x1dv0\x00\x01\x00\x02\x00\x03\x04" +
+		"\x1dv0\x00\x01\x00\x01\x00\x05"
+	if got := string(w.GetWritten()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	// with flow control, the printer is queried between bands
+	w = NewMockWriter()
+	w.buffer.WriteString("\x00\x00")
+	p, _ = NewPrinter(w, WithRasterBandHeight(2), WithFlowControl())
+	if err := p.Raster(8, 5, 1, img, raster.BitImage); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected = "" +
+		"\x1dv0\x00\x01\x00\x02\x00\x01\x02\x1dr\x01" +
+		"\x1dv0\x00\x01\x00\x02\x00\x03\x04\x1dr\x01" +
+		"\x1dv0\x00\x01\x00\x01\x00\x05"
+	if got := string(w.GetWritten()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	// no response from the printer
+	p, _ = NewPrinter(NewMockWriter(), WithRasterBandHeight(2), WithFlowControl())
+	if err := p.Raster(8, 5, 1, img, raster.BitImage); err == nil {
+		t.Error("Expected error without a status response")
+	}
+
+	if err := p.SetRasterBandHeight(-1); err == nil {
+		t.Error("Expected error for negative band height")
+	}
+	if _, err := NewPrinter(NewMockWriter(), WithRasterBandHeight(-1)); err == nil {
+		t.Error("Expected error for negative band height option")
+	}
+}
@@ -1,44 +1,63 @@
 package escpos
 
 import (
-	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"time"
 )
 
 const (
 	// DefaultEndpoint is the default server endpoint for ePOS printers.
 	DefaultEndpoint = "/cgi-bin/epos/service.cgi"
+
+	// statusTimeout is how long the server waits for the printer status.
+	statusTimeout = 5 * time.Second
 )
 
 // Server wrap
 type Server struct {
 	p      *Printer
-	w      *bufio.Writer
 	logger func(string, ...interface{})
+
+	// status enables reading the printer status after each job
+	status bool
+
+	// timeout bounds each job, when not zero
+	timeout time.Duration
 }
 
 // NewServer creates a new ePOS server.
 func NewServer(w io.ReadWriter, opts ...ServerOption) (*Server, error) {
-	var err error
-
 	// create printer
 	p, err := NewPrinter(w)
 	if err != nil {
 		return nil, err
 	}
 
+	return NewPrinterServer(p, opts...)
+}
+
+// NewPrinterServer creates a new ePOS server printing with p, keeping the
+// options of p, such as its profile.
+func NewPrinterServer(p *Printer, opts ...ServerOption) (*Server, error) {
+	if p == nil {
+		return nil, errors.New("must supply valid printer")
+	}
+
 	s := &Server{
 		p: p,
-		w: bufio.NewWriter(p),
 	}
 
 	// apply opts
 	for _, o := range opts {
-		err = o(s)
-		if err != nil {
+		if err := o(s); err != nil {
 			return nil, err
 		}
 	}
@@ -87,35 +106,230 @@ func (s *Server) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// init printer
-	s.p.Init()
+	// bound the job by the job timeout if any, and sending it by the request
+	// too, as the status of a sent job is read even when the client is gone
+	jobCtx, ctx := context.Background(), req.Context()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		jobCtx, cancel = context.WithTimeout(jobCtx, s.timeout)
+		defer cancel()
+		deadline, _ := jobCtx.Deadline()
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
 
-	// loop over nodes
-	for _, n := range nodes {
-		// grab parameters
-		params := n.Attributes()
+	// collect the job and send it holding the printer lock, as the other
+	// requests share the printer, its state and its error
+	s.p.Lock()
+	job, err := s.newJob(nodes)
 
-		// write data to printer
-		s.p.WriteNode(n.Name(), params, n.Content)
+	// clear any error left over from a previous job, then print the job
+	s.p.ClearErr()
+	if err == nil {
+		err = job.commit(ctx)
 	}
 
-	// end
-	s.p.End()
+	// the printer may have failed even if the node reported no error
+	perr := s.p.Err()
+	if err == nil {
+		err = perr
+	}
 
-	// flush writer
-	s.w.Flush()
+	// read the printer status once the job is sent
+	var asb uint32
+	if err == nil && s.status {
+		var serr error
+		if asb, serr = s.readStatus(jobCtx); serr != nil {
+			s.logger("cannot read printer status: %v", serr)
+			asb = asbNoResponse
+		}
+	}
+	s.p.Unlock()
+
+	code := responseCode(err, perr, asb)
+	if err != nil {
+		s.logger("print failed: %v", err)
+	}
+	if code == "" {
+		asb |= asbPrintSuccess
+	} else if perr != nil {
+		asb |= asbNoResponse
+	}
 
 	// write soap response
+	var jobID bytes.Buffer
+	xml.EscapeText(&jobID, []byte(getPrintJobID(body)))
 	res.Header().Set("Content-Type", req.Header.Get("Content-Type"))
-	fmt.Fprintf(res, soapBody, true, "")
+	fmt.Fprintf(res, soapBody, code == "", code, asb, 0, jobID.String())
 }
 
+// newJob collects the commands of the nodes in a print job. The printer lock
+// must be held.
+func (s *Server) newJob(nodes []Node) (*Job, error) {
+	job, err := s.p.newJob()
+	if err != nil {
+		return nil, err
+	}
+
+	// init printer
+	err = job.Init()
+
+	// write nodes to printer
+	if err == nil {
+		err = job.WriteNodes(nodes)
+	}
+
+	// end
+	if err == nil {
+		err = job.End()
+	}
+
+	if err != nil {
+		job.Discard()
+		return nil, err
+	}
+	return job, nil
+}
+
+// readStatus reads the printer status and returns it as an ePOS-Print
+// status bitfield.
+func (s *Server) readStatus(ctx context.Context) (uint32, error) {
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+
+	st, err := s.p.Status(ctx)
+	if err == ErrNoReadDeadline {
+		// the status cannot be read without risking blocking the server, as
+		// on a device file, so the job is reported as sent
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var asb uint32
+	for _, b := range []struct {
+		on  bool
+		bit uint32
+	}{
+		{st.DrawerPin, asbDrawerKick},
+		{!st.Online, asbOffLine},
+		{st.CoverOpen, asbCoverOpen},
+		{st.PaperFeeding, asbPaperFeed},
+		{st.WaitingOnline, asbWaitOnLine},
+		{st.FeedButton, asbPanelSwitch},
+		{st.MechanicalError, asbMechanicalErr},
+		{st.CutterError, asbAutocutterErr},
+		{st.UnrecoverableError, asbUnrecoverErr},
+		{st.AutoRecoverableError, asbAutorecoverErr},
+		{st.PaperNearEnd, asbReceiptNearEnd},
+		{st.PaperEnd || st.PaperEndStop, asbReceiptEnd},
+	} {
+		if b.on {
+			asb |= b.bit
+		}
+	}
+	return asb, nil
+}
+
+// isPortError returns true when err, raised by the printer connection, is
+// not a timeout.
+func isPortError(err error) bool {
+	if err == nil {
+		return false
+	}
+	ne, ok := err.(net.Error)
+	return !ok || !ne.Timeout()
+}
+
+// responseCode returns the ePOS-Print response code of a job that ended with
+// err, the printer connection error perr and the status asb, or the empty
+// string when the job succeeded.
+func responseCode(err, perr error, asb uint32) string {
+	switch {
+	case perr != nil && !isPortError(perr):
+		return codeTimeout
+	case perr != nil:
+		return codePortError
+	case err != nil:
+		if _, ok := err.(*ElementError); ok {
+			return codeSchemaError
+		}
+		return codePrintSystemError
+	}
+
+	for _, c := range asbCodes {
+		if asb&c.bit != 0 {
+			return c.code
+		}
+	}
+	return ""
+}
+
+// ePOS-Print status bits, as returned in the status attribute of a response.
+const (
+	asbNoResponse     uint32 = 0x00000001
+	asbPrintSuccess   uint32 = 0x00000002
+	asbDrawerKick     uint32 = 0x00000004
+	asbOffLine        uint32 = 0x00000008
+	asbCoverOpen      uint32 = 0x00000020
+	asbPaperFeed      uint32 = 0x00000040
+	asbWaitOnLine     uint32 = 0x00000100
+	asbPanelSwitch    uint32 = 0x00000200
+	asbMechanicalErr  uint32 = 0x00000400
+	asbAutocutterErr  uint32 = 0x00000800
+	asbUnrecoverErr   uint32 = 0x00002000
+	asbAutorecoverErr uint32 = 0x00004000
+	asbReceiptNearEnd uint32 = 0x00020000
+	asbReceiptEnd     uint32 = 0x00080000
+)
+
+// asbCodes are the ePOS-Print codes reported for the status bits that fail a
+// job, in order of precedence.
+var asbCodes = []struct {
+	bit  uint32
+	code string
+}{
+	{asbNoResponse, codePortError},
+	{asbCoverOpen, codeCoverOpen},
+	{asbReceiptEnd, codeReceiptEmpty},
+	{asbMechanicalErr, codeMechanical},
+	{asbAutocutterErr, codeAutocutter},
+	{asbUnrecoverErr, codeUnrecoverable},
+	{asbAutorecoverErr, codeAutoRecoverable},
+	{asbOffLine, codePrintSystemError},
+}
+
+const (
+	// codePortError is the ePOS-Print code for a communication port error.
+	codePortError = "EX_BADPORT"
+
+	// codeTimeout is the ePOS-Print code for a printer that timed out.
+	codeTimeout = "EX_TIMEOUT"
+
+	// codePrintSystemError is the ePOS-Print code for a generic print system
+	// error.
+	codePrintSystemError = "PrintSystemError"
+
+	// codeSchemaError is the ePOS-Print code for a request with unknown or
+	// misplaced elements.
+	codeSchemaError = "SchemaError"
+
+	// Printer error codes.
+	codeCoverOpen       = "EPTR_COVER_OPEN"
+	codeReceiptEmpty    = "EPTR_REC_EMPTY"
+	codeMechanical      = "EPTR_MECHANICAL"
+	codeAutocutter      = "EPTR_AUTOCUTTER"
+	codeUnrecoverable   = "EPTR_UNRECOVERABLE"
+	codeAutoRecoverable = "EPTR_AUTOMATICAL"
+)
+
 const (
 	// soapBody is a basic SOAP response body for an ePOS server response.
 	soapBody = `<?xml version="1.0" encoding="utf-8"?>
 <s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
   <s:Body xmlns:m="http://www.epson-pos.com/schemas/2011/03/epos-print">
-	<m:response success="%t" code="%s" status="0"></m:response>
+	<m:response success="%t" code="%s" status="%d" battery="%d" printjobid="%s"></m:response>
   </s:Body>
 </s:Envelope>`
 )
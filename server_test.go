@@ -2,10 +2,14 @@ package escpos
 
 import (
 	"bytes"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // MockWriter implements io.ReadWriter for testing
@@ -29,6 +33,12 @@ func (m *MockWriter) Read(p []byte) (n int, err error) {
 	return m.buffer.Read(p)
 }
 
+// SetReadDeadline satisfies the readDeadliner interface; reads from the
+// buffer never block.
+func (m *MockWriter) SetReadDeadline(time.Time) error {
+	return nil
+}
+
 func (m *MockWriter) GetWritten() []byte {
 	return m.written
 }
@@ -182,6 +192,153 @@ func TestServerErrorHandling(t *testing.T) {
 	}
 }
 
+// Test the status, code and print job ID of ePOS responses
+func TestServerResponse(t *testing.T) {
+	request := `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Header>
+    <parameter xmlns="http://www.epson-pos.com/schemas/2011/03/epos-print">
+      <devid>local_printer</devid>
+      <printjobid>job&amp;1</printjobid>
+    </parameter>
+  </s:Header>
+  <s:Body>
+    <epos-print xmlns="http://www.epson-pos.com/schemas/2011/03/epos-print">
+      <%s/>
+    </epos-print>
+  </s:Body>
+</s:Envelope>`
+
+	testCases := []struct {
+		name     string
+		element  string
+		status   string
+		expected string
+	}{
+		{"Success", "cut", "\x12\x12\x12\x12",
+			`success="true" code="" status="2" battery="0" printjobid="job&amp;1"`},
+		{"Near end", "cut", "\x12\x12\x12\x1e",
+			`success="true" code="" status="131074"`},
+		{"Cover open", "cut", "\x1a\x16\x12\x12",
+			`success="false" code="EPTR_COVER_OPEN" status="40"`},
+		{"Paper end", "cut", "\x1a\x32\x12\x72",
+			`success="false" code="EPTR_REC_EMPTY" status="524296"`},
+		{"No response", "cut", "",
+			`success="false" code="EX_BADPORT" status="1"`},
+		{"Unknown element", "bogus", "",
+			`success="false" code="SchemaError" status="0"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockWriter := NewMockWriter()
+			mockWriter.buffer.WriteString(tc.status)
+			server, err := NewServer(mockWriter, WithStatus())
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+
+			req := httptest.NewRequest("POST", DefaultEndpoint, strings.NewReader(fmt.Sprintf(request, tc.element)))
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, req)
+
+			if !strings.Contains(w.Body.String(), tc.expected) {
+				t.Errorf("Expected response containing %q, got %q", tc.expected, w.Body.String())
+			}
+		})
+	}
+}
+
+// Test that jobs succeed when the status cannot be read without deadlines
+func TestServerStatusNoReadDeadline(t *testing.T) {
+	mockWriter := NewMockWriter()
+	server, err := NewServer(&struct{ io.ReadWriter }{mockWriter}, WithStatus())
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	soapBody := `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>` +
+		`<epos-print xmlns="http://www.epson-pos.com/schemas/2011/03/epos-print"><cut/></epos-print>` +
+		`</s:Body></s:Envelope>`
+	req := httptest.NewRequest("POST", DefaultEndpoint, strings.NewReader(soapBody))
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if expected := `success="true" code=""`; !strings.Contains(w.Body.String(), expected) {
+		t.Errorf("Expected response containing %q, got %q", expected, w.Body.String())
+	}
+	if bytes.Contains(mockWriter.GetWritten(), []byte{0x10, 0x04}) {
+		t.Errorf("Expected the job without status requests, got %q", mockWriter.GetWritten())
+	}
+}
+
+// Test that the server prints with the options of its printer
+func TestServerPrinterProfile(t *testing.T) {
+	pr, _ := LookupProfile("POS-5890")
+	mockWriter := NewMockWriter()
+	p, _ := NewPrinter(mockWriter, WithProfile(pr))
+	server, err := NewPrinterServer(p)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	soapBody := `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <epos-print xmlns="http://www.epson-pos.com/schemas/2011/03/epos-print">
+      <symbol type="qrcode_model_2">hello</symbol>
+      <cut/>
+    </epos-print>
+  </s:Body>
+</s:Envelope>`
+
+	req := httptest.NewRequest("POST", DefaultEndpoint, strings.NewReader(soapBody))
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if !strings.Contains(w.Body.String(), `success="true"`) {
+		t.Fatalf("Expected success, got %q", w.Body.String())
+	}
+	written := string(mockWriter.GetWritten())
+	if strings.Contains(written, "\x1d(k") || strings.Contains(written, "\x1dV") {
+		t.Errorf("Expected no QR code or cut commands, got %q", written)
+	}
+	if !strings.Contains(written, "\x1dv0") {
+		t.Errorf("Expected raster QR code, got %q", written)
+	}
+
+	if _, err := NewPrinterServer(nil); err == nil {
+		t.Error("Expected error for nil printer")
+	}
+}
+
+// Test that concurrent requests share the printer safely
+func TestServerConcurrentRequests(t *testing.T) {
+	server, err := NewServer(NewMockWriter())
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	soapBody := `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>` +
+		`<epos-print xmlns="http://www.epson-pos.com/schemas/2011/03/epos-print"><cut/></epos-print>` +
+		`</s:Body></s:Envelope>`
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", DefaultEndpoint, strings.NewReader(soapBody))
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, req)
+			if !strings.Contains(w.Body.String(), `success="true"`) {
+				t.Errorf("Expected success, got %q", w.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 // Test CORS handling
 func TestServerCORS(t *testing.T) {
 	mockWriter := NewMockWriter()
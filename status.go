@@ -0,0 +1,266 @@
+package escpos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrInvalidStatus is returned when the printer answers a status request with
+// a byte that is not a valid status.
+var ErrInvalidStatus = errors.New("invalid printer status")
+
+// ErrNoReadDeadline is returned by reads bounded by a context, such as Status
+// with a timeout, on printer connections that do not support read deadlines.
+var ErrNoReadDeadline = errors.New("printer connection does not support read deadlines")
+
+// drainTimeout is how long the printer is read for late replies to requests
+// whose reads were given up.
+const drainTimeout = 50 * time.Millisecond
+
+// Real-time status types, as used by DLE EOT.
+const (
+	statusPrinter   = 1
+	statusOffline   = 2
+	statusError     = 3
+	statusRollPaper = 4
+	statusInk       = 7
+	statusPeeler    = 8
+)
+
+// PrinterStatus is the real-time status of the printer.
+type PrinterStatus struct {
+	// Online is true when the printer is online.
+	Online bool
+
+	// DrawerPin is true when pin 3 of the drawer kick-out connector is high,
+	// which usually means the drawer is closed.
+	DrawerPin bool
+
+	// WaitingOnline is true when the printer is waiting for online recovery.
+	WaitingOnline bool
+
+	// FeedButton is true when the paper feed button is pressed.
+	FeedButton bool
+
+	// CoverOpen is true when the printer cover is open.
+	CoverOpen bool
+
+	// PaperFeeding is true when paper is being fed with the feed button.
+	PaperFeeding bool
+
+	// PaperEndStop is true when printing stopped at the end of the paper.
+	PaperEndStop bool
+
+	// Error is true when an error occurred.
+	Error bool
+
+	// MechanicalError is true when a recoverable mechanical error occurred.
+	MechanicalError bool
+
+	// CutterError is true when an autocutter error occurred.
+	CutterError bool
+
+	// UnrecoverableError is true when an unrecoverable error occurred.
+	UnrecoverableError bool
+
+	// AutoRecoverableError is true when an error that recovers by itself,
+	// such as the head overheating, occurred.
+	AutoRecoverableError bool
+
+	// PaperNearEnd is true when the roll paper near-end sensor detects the
+	// paper running out.
+	PaperNearEnd bool
+
+	// PaperEnd is true when the roll paper end sensor detects no paper.
+	PaperEnd bool
+
+	// InkNearEnd and InkEnd are true when the ink of either colour is near
+	// its end or has run out. They are only read with extended status.
+	InkNearEnd, InkEnd bool
+
+	// LabelWaiting is true when a peeled label waits to be removed. It is
+	// only read with extended status.
+	LabelWaiting bool
+}
+
+// Status reads the real-time status of the printer (DLE EOT 1 to 4, and 7 and
+// 8 with extended status). Reads give up when ctx is done, using read
+// deadlines; on printer connections without them, Status fails with
+// ErrNoReadDeadline, before sending any request, unless ctx can never be done.
+func (p *Printer) Status(ctx context.Context) (PrinterStatus, error) {
+	var st PrinterStatus
+
+	if err := p.checkReadDeadline(ctx); err != nil {
+		return st, err
+	}
+
+	b, err := p.realtimeStatus(ctx, statusPrinter)
+	if err != nil {
+		return st, err
+	}
+	st.DrawerPin = b&0x04 != 0
+	st.Online = b&0x08 == 0
+	st.WaitingOnline = b&0x20 != 0
+	st.FeedButton = b&0x40 != 0
+
+	if b, err = p.realtimeStatus(ctx, statusOffline); err != nil {
+		return st, err
+	}
+	st.CoverOpen = b&0x04 != 0
+	st.PaperFeeding = b&0x08 != 0
+	st.PaperEndStop = b&0x20 != 0
+	st.Error = b&0x40 != 0
+
+	if b, err = p.realtimeStatus(ctx, statusError); err != nil {
+		return st, err
+	}
+	st.MechanicalError = b&0x04 != 0
+	st.CutterError = b&0x08 != 0
+	st.UnrecoverableError = b&0x20 != 0
+	st.AutoRecoverableError = b&0x40 != 0
+
+	if b, err = p.realtimeStatus(ctx, statusRollPaper); err != nil {
+		return st, err
+	}
+	st.PaperNearEnd = b&0x0c != 0
+	st.PaperEnd = b&0x60 != 0
+
+	if !p.extendedStatus {
+		return st, nil
+	}
+
+	if b, err = p.realtimeStatus(ctx, statusInk, 1); err != nil {
+		return st, err
+	}
+	st.InkNearEnd = b&0x0c != 0
+	st.InkEnd = b&0x60 != 0
+
+	if b, err = p.realtimeStatus(ctx, statusPeeler, 3); err != nil {
+		return st, err
+	}
+	st.LabelWaiting = b&0x04 != 0
+
+	return st, nil
+}
+
+// ReadStatus returns true when the printer is online, giving up after a
+// second. It returns false on connections without read deadlines.
+//
+// Deprecated: use Status.
+func (p *Printer) ReadStatus() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	st, err := p.Status(ctx)
+	return err == nil && st.Online
+}
+
+// realtimeStatus requests the real-time status n from the printer (DLE EOT)
+// and returns it. The printer's writer must not be buffered.
+func (p *Printer) realtimeStatus(ctx context.Context, n byte, a ...byte) (byte, error) {
+	if err := p.request(ctx, append([]byte{0x10, 0x04, n}, a...)); err != nil {
+		return 0, err
+	}
+
+	b := make([]byte, 1)
+	if err := p.readFull(ctx, b); err != nil {
+		return 0, err
+	}
+
+	// bits 1 and 4 are always set, bits 0 and 7 always clear
+	if b[0]&0x93 != 0x12 {
+		return 0, ErrInvalidStatus
+	}
+	return b[0], nil
+}
+
+// readFull reads exactly len(buf) bytes from the printer, or from its status
+// monitor while it runs, giving up when ctx is done. Reads that can be given
+// up on need a connection supporting read deadlines, and fail with
+// ErrNoReadDeadline otherwise. A reply to a read given up on is discarded
+// before the next request.
+func (p *Printer) readFull(ctx context.Context, buf []byte) (err error) {
+	defer func() {
+		if err != nil && err != ErrNoReadDeadline {
+			p.stale = true
+		}
+		err = timeoutError(ctx, "read", err)
+	}()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if m := p.currentMonitor(); m != nil {
+		return m.read(ctx, buf)
+	}
+
+	if d, ok := p.w.(readDeadliner); ok {
+		if restore, ok := setDeadline(ctx, d.SetReadDeadline); ok {
+			defer restore()
+			_, err := io.ReadFull(p.w, buf)
+			return err
+		}
+	}
+
+	// a read in the background would compete with the later reads
+	if ctx.Done() != nil {
+		return ErrNoReadDeadline
+	}
+	_, err = io.ReadFull(p.w, buf)
+	return err
+}
+
+// checkReadDeadline returns ErrNoReadDeadline when reads from the printer
+// cannot be given up on once ctx is done.
+func (p *Printer) checkReadDeadline(ctx context.Context) error {
+	if ctx.Done() == nil || p.currentMonitor() != nil {
+		return nil
+	}
+	if d, ok := p.w.(readDeadliner); ok && d.SetReadDeadline(time.Time{}) == nil {
+		return nil
+	}
+	return ErrNoReadDeadline
+}
+
+// request discards the stale replies of the printer, and sends the request
+// buf, giving up when ctx is done.
+func (p *Printer) request(ctx context.Context, buf []byte) error {
+	p.discardStale()
+	return p.writeContext(ctx, buf)
+}
+
+// discardStale discards the bytes sent by the printer that no read is waiting
+// for, such as the late reply to a request whose read was given up, so that
+// they are not taken as the reply to the next request.
+func (p *Printer) discardStale() {
+	stale := p.stale
+	p.stale = false
+
+	if m := p.currentMonitor(); m != nil {
+		m.drain(stale)
+		return
+	}
+	if !stale {
+		return
+	}
+
+	d, ok := p.w.(readDeadliner)
+	if !ok || d.SetReadDeadline(time.Now().Add(drainTimeout)) != nil {
+		return
+	}
+	defer d.SetReadDeadline(time.Time{})
+	buf := make([]byte, 64)
+	for {
+		if _, err := p.w.Read(buf); err != nil {
+			return
+		}
+	}
+}
+
+// currentMonitor returns the running status monitor, if any.
+func (p *Printer) currentMonitor() *Monitor {
+	p.monitorMu.Lock()
+	defer p.monitorMu.Unlock()
+	return p.monitor
+}
@@ -0,0 +1,144 @@
+package escpos
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStatus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     []PrinterOption
+		script   string
+		expected PrinterStatus
+		written  string
+	}{
+		{"Online", nil, "\x12\x12\x12\x12",
+			PrinterStatus{Online: true},
+			"\x10\x04\x01\x10\x04\x02\x10\x04\x03\x10\x04\x04"},
+		{"Cover open", nil, "\x1e\x56\x12\x1e",
+			PrinterStatus{DrawerPin: true, CoverOpen: true, Error: true, PaperNearEnd: true},
+			"\x10\x04\x01\x10\x04\x02\x10\x04\x03\x10\x04\x04"},
+		{"Errors", nil, "\x7a\x72\x7e\x72",
+			PrinterStatus{WaitingOnline: true, FeedButton: true, PaperEndStop: true, Error: true,
+				MechanicalError: true, CutterError: true, UnrecoverableError: true, AutoRecoverableError: true, PaperEnd: true},
+			"\x10\x04\x01\x10\x04\x02\x10\x04\x03\x10\x04\x04"},
+		{"Extended", []PrinterOption{WithExtendedStatus()}, "\x12\x12\x12\x12\x16\x16",
+			PrinterStatus{Online: true, InkNearEnd: true, LabelWaiting: true},
+			"\x10\x04\x01\x10\x04\x02\x10\x04\x03\x10\x04\x04\x10\x04\x07\x01\x10\x04\x08\x03"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewMockWriter()
+			w.buffer.WriteString(tc.script)
+			p, _ := NewPrinter(w, tc.opts...)
+
+			st, err := p.Status(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if st != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, st)
+			}
+			if got := string(w.GetWritten()); got != tc.written {
+				t.Errorf("Expected %q, got %q", tc.written, got)
+			}
+		})
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	w := NewMockWriter()
+	w.buffer.WriteString("\x13")
+	p, _ := NewPrinter(w)
+	if _, err := p.Status(context.Background()); err != ErrInvalidStatus {
+		t.Errorf("Expected ErrInvalidStatus, got %v", err)
+	}
+
+	p, _ = NewPrinter(NewMockWriter())
+	if p.ReadStatus() {
+		t.Error("Expected printer without response to be offline")
+	}
+}
+
+func TestStatusDeadline(t *testing.T) {
+	conn, printer := net.Pipe()
+	defer conn.Close()
+	defer printer.Close()
+
+	// the printer reads the request, but never answers
+	go func() {
+		buf := make([]byte, 3)
+		for {
+			if _, err := printer.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	p, _ := NewPrinter(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := p.Status(ctx); err == nil {
+		t.Fatal("Expected error")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Status took %v", d)
+	}
+}
+
+func TestStatusLateReply(t *testing.T) {
+	conn, printer := net.Pipe()
+	defer conn.Close()
+	defer printer.Close()
+
+	// the printer answers the first request once its read was given up, then
+	// answers the others in time
+	late := make(chan struct{})
+	go func() {
+		buf := make([]byte, 3)
+		if _, err := io.ReadFull(printer, buf); err != nil {
+			return
+		}
+		<-late
+		printer.Write([]byte{0x1e})
+		for {
+			if _, err := io.ReadFull(printer, buf); err != nil {
+				return
+			}
+			printer.Write([]byte{0x12})
+		}
+	}()
+
+	p, _ := NewPrinter(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.Status(ctx); err == nil {
+		t.Fatal("Expected error")
+	}
+	close(late)
+
+	st, err := p.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !st.Online || st.PaperNearEnd {
+		t.Errorf("Expected late reply to be discarded, got %+v", st)
+	}
+}
+
+func TestStatusNoReadDeadline(t *testing.T) {
+	w := &struct{ io.ReadWriter }{NewMockWriter()}
+	p, _ := NewPrinter(w)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := p.Status(ctx); err != ErrNoReadDeadline {
+		t.Errorf("Expected ErrNoReadDeadline, got %v", err)
+	}
+}
@@ -0,0 +1,669 @@
+package escpos
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// SymbolType is a 2D symbol type, as used by GS ( k (the cn parameter).
+type SymbolType byte
+
+// 2D symbol types.
+const (
+	SymbolPDF417     SymbolType = 48
+	SymbolQRCode     SymbolType = 49
+	SymbolMaxiCode   SymbolType = 50
+	SymbolGS1DataBar SymbolType = 51
+	SymbolComposite  SymbolType = 52
+	SymbolAztec      SymbolType = 53
+	SymbolDataMatrix SymbolType = 54
+)
+
+// symbolNames are the display names of the 2D symbol types.
+var symbolNames = map[SymbolType]string{
+	SymbolPDF417:     "PDF417",
+	SymbolQRCode:     "QR Code",
+	SymbolMaxiCode:   "MaxiCode",
+	SymbolGS1DataBar: "GS1 DataBar",
+	SymbolComposite:  "Composite Symbology",
+	SymbolAztec:      "Aztec Code",
+	SymbolDataMatrix: "DataMatrix",
+}
+
+// String satisfies the fmt.Stringer interface.
+func (t SymbolType) String() string {
+	if s, ok := symbolNames[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("SymbolType(%d)", byte(t))
+}
+
+// symbolFunc is a single GS ( k function with its parameters.
+type symbolFunc struct {
+	fn     byte
+	params []byte
+}
+
+// SymbolOptions are the options for a 2D symbol. It is implemented by
+// QROptions, PDF417Options, MaxiCodeOptions, GS1DataBarOptions,
+// CompositeOptions, AztecOptions and DataMatrixOptions. A nil pointer of any
+// of those types selects the default options.
+type SymbolOptions interface {
+	// symbolType returns the symbol type the options apply to.
+	symbolType() SymbolType
+
+	// functions validates data and returns the functions that set up the
+	// symbol and store data in the symbol storage area.
+	functions(data string) ([]symbolFunc, error)
+}
+
+// storeData returns the function storing data in the symbol storage area
+// (function 80), prefixed with prefix.
+func storeData(data string, prefix ...byte) symbolFunc {
+	return symbolFunc{80, append(append([]byte{48}, prefix...), data...)}
+}
+
+// symbolSend sends a GS ( k command for the symbol type cn with function fn
+// and the supplied parameters.
+func (p *Printer) symbolSend(cn SymbolType, fn byte, params ...byte) error {
+	l := len(params) + 2
+
+	if err := p.write([]byte{0x1d, '(', 'k', byte(l % 256), byte(l / 256), byte(cn), fn}); err != nil {
+		return err
+	}
+	return p.write(params)
+}
+
+// Symbol prints data as a 2D symbol of type kind using the printer's native
+// 2D symbol support (GS ( k). When opts is nil, the default options for kind
+// are used. QR codes are printed as raster images when the printer's profile
+// lacks native QR code support.
+func (p *Printer) Symbol(kind SymbolType, data string, opts SymbolOptions) error {
+	if opts == nil {
+		switch kind {
+		case SymbolPDF417:
+			opts = &PDF417Options{}
+		case SymbolQRCode:
+			opts = &QROptions{}
+		case SymbolMaxiCode:
+			opts = &MaxiCodeOptions{}
+		case SymbolGS1DataBar:
+			opts = &GS1DataBarOptions{}
+		case SymbolComposite:
+			opts = &CompositeOptions{}
+		case SymbolAztec:
+			opts = &AztecOptions{}
+		case SymbolDataMatrix:
+			opts = &DataMatrixOptions{}
+		default:
+			return fmt.Errorf("unsupported symbol type: %s", kind)
+		}
+	}
+
+	if opts.symbolType() != kind {
+		return fmt.Errorf("%s options cannot be used for %s symbol", opts.symbolType(), kind)
+	}
+
+	// QR codes fall back to raster images on printers without native support
+	switch {
+	case kind == SymbolQRCode && !p.supports(FeatureQRCode):
+		return p.QRCodeRaster(data, opts.(*QROptions))
+	case kind != SymbolQRCode && !p.supports(FeatureSymbols):
+		return p.unsupported(FeatureSymbols)
+	}
+
+	fns, err := opts.functions(data)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fns {
+		if err = p.symbolSend(kind, f.fn, f.params...); err != nil {
+			return err
+		}
+	}
+
+	// function 81, print the symbol data in the symbol storage area
+	return p.symbolSend(kind, 81, 48)
+}
+
+// checkRange returns an error when v is not between min and max.
+func checkRange(name string, v, min, max int) error {
+	if v < min || v > max {
+		return fmt.Errorf("%s must be between %d and %d", name, min, max)
+	}
+	return nil
+}
+
+// checkData returns an error when data is empty or longer than max bytes.
+func checkData(kind SymbolType, data string, max int) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%s data is empty", kind)
+	}
+	if len(data) > max {
+		return fmt.Errorf("%s data too long", kind)
+	}
+	return nil
+}
+
+// PDF417ErrorCorrection is a PDF417 error correction level.
+type PDF417ErrorCorrection byte
+
+// PDF417 error correction levels.
+const (
+	PDF417Level0 PDF417ErrorCorrection = 48 + iota
+	PDF417Level1
+	PDF417Level2
+	PDF417Level3
+	PDF417Level4
+	PDF417Level5
+	PDF417Level6
+	PDF417Level7
+	PDF417Level8
+)
+
+// PDF417Options are the options used when printing a PDF417 symbol.
+type PDF417Options struct {
+	// Columns is the number of data columns (1-30), or 0 for automatic.
+	Columns int
+
+	// Rows is the number of rows (3-90), or 0 for automatic.
+	Rows int
+
+	// ModuleWidth is the module width, in dots (2-8). Defaults to 3.
+	ModuleWidth int
+
+	// RowHeight is the row height, as a multiple of the module width (2-8).
+	// Defaults to 3.
+	RowHeight int
+
+	// ErrorCorrection is the error correction level. Defaults to level 1.
+	ErrorCorrection PDF417ErrorCorrection
+
+	// Truncated selects truncated PDF417.
+	Truncated bool
+}
+
+func (o *PDF417Options) symbolType() SymbolType {
+	return SymbolPDF417
+}
+
+func (o *PDF417Options) functions(data string) ([]symbolFunc, error) {
+	var opts PDF417Options
+	if o != nil {
+		opts = *o
+	}
+	if opts.ModuleWidth == 0 {
+		opts.ModuleWidth = 3
+	}
+	if opts.RowHeight == 0 {
+		opts.RowHeight = 3
+	}
+	if opts.ErrorCorrection == 0 {
+		opts.ErrorCorrection = PDF417Level1
+	}
+
+	if err := checkData(SymbolPDF417, data, 65532); err != nil {
+		return nil, err
+	}
+	if err := checkRange("PDF417 columns", opts.Columns, 0, 30); err != nil {
+		return nil, err
+	}
+	if opts.Rows != 0 {
+		if err := checkRange("PDF417 rows", opts.Rows, 3, 90); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkRange("PDF417 module width", opts.ModuleWidth, 2, 8); err != nil {
+		return nil, err
+	}
+	if err := checkRange("PDF417 row height", opts.RowHeight, 2, 8); err != nil {
+		return nil, err
+	}
+	if opts.ErrorCorrection < PDF417Level0 || opts.ErrorCorrection > PDF417Level8 {
+		return nil, errors.New("invalid PDF417 error correction level")
+	}
+
+	var truncated byte
+	if opts.Truncated {
+		truncated = 1
+	}
+
+	return []symbolFunc{
+		{65, []byte{byte(opts.Columns)}},
+		{66, []byte{byte(opts.Rows)}},
+		{67, []byte{byte(opts.ModuleWidth)}},
+		{68, []byte{byte(opts.RowHeight)}},
+		{69, []byte{48, byte(opts.ErrorCorrection)}},
+		{70, []byte{truncated}},
+		storeData(data),
+	}, nil
+}
+
+// MaxiCodeOptions are the options used when printing a MaxiCode symbol.
+type MaxiCodeOptions struct {
+	// Mode is the MaxiCode mode (2-6). Defaults to mode 2.
+	Mode int
+}
+
+func (o *MaxiCodeOptions) symbolType() SymbolType {
+	return SymbolMaxiCode
+}
+
+func (o *MaxiCodeOptions) functions(data string) ([]symbolFunc, error) {
+	mode := 2
+	if o != nil && o.Mode != 0 {
+		mode = o.Mode
+	}
+
+	if err := checkData(SymbolMaxiCode, data, 138); err != nil {
+		return nil, err
+	}
+	if err := checkRange("MaxiCode mode", mode, 2, 6); err != nil {
+		return nil, err
+	}
+
+	return []symbolFunc{
+		{65, []byte{byte(48 + mode)}},
+		storeData(data),
+	}, nil
+}
+
+// GS1DataBarType is a stacked GS1 DataBar symbol type.
+type GS1DataBarType byte
+
+// Stacked GS1 DataBar symbol types.
+const (
+	GS1DataBarStacked                GS1DataBarType = 72
+	GS1DataBarStackedOmnidirectional GS1DataBarType = 73
+	GS1DataBarExpandedStacked        GS1DataBarType = 76
+)
+
+// GS1DataBarOptions are the options used when printing a stacked GS1 DataBar
+// symbol.
+type GS1DataBarOptions struct {
+	// Type is the symbol type. Defaults to GS1DataBarStacked.
+	Type GS1DataBarType
+
+	// ModuleWidth is the module width, in dots (2-8). Defaults to 2.
+	ModuleWidth int
+
+	// MaxWidth is the maximum width of a GS1 DataBar Expanded Stacked symbol,
+	// in dots (106-3280), or 0 for no limit.
+	MaxWidth int
+}
+
+func (o *GS1DataBarOptions) symbolType() SymbolType {
+	return SymbolGS1DataBar
+}
+
+func (o *GS1DataBarOptions) functions(data string) ([]symbolFunc, error) {
+	var opts GS1DataBarOptions
+	if o != nil {
+		opts = *o
+	}
+	if opts.Type == 0 {
+		opts.Type = GS1DataBarStacked
+	}
+	if opts.ModuleWidth == 0 {
+		opts.ModuleWidth = 2
+	}
+
+	if err := checkData(SymbolGS1DataBar, data, 255); err != nil {
+		return nil, err
+	}
+	switch opts.Type {
+	case GS1DataBarStacked, GS1DataBarStackedOmnidirectional:
+		// the printer calculates the check digit
+		if !isDigits(data) || len(data) != 13 {
+			return nil, errors.New("stacked GS1 DataBar data must be 13 digits")
+		}
+	case GS1DataBarExpandedStacked:
+	default:
+		return nil, errors.New("invalid GS1 DataBar type")
+	}
+	if err := checkRange("GS1 DataBar module width", opts.ModuleWidth, 2, 8); err != nil {
+		return nil, err
+	}
+	if opts.MaxWidth != 0 {
+		if err := checkRange("GS1 DataBar maximum width", opts.MaxWidth, 106, 3280); err != nil {
+			return nil, err
+		}
+	}
+
+	return []symbolFunc{
+		{67, []byte{byte(opts.ModuleWidth)}},
+		{71, intLowHigh(opts.MaxWidth, 2)},
+		storeData(data, byte(opts.Type)),
+	}, nil
+}
+
+// CompositeLinear is the linear component type of a composite symbol.
+type CompositeLinear byte
+
+// Composite symbol linear component types.
+const (
+	CompositeEAN8                             CompositeLinear = 65
+	CompositeEAN13                            CompositeLinear = 66
+	CompositeUPCA                             CompositeLinear = 67
+	CompositeUPCE6                            CompositeLinear = 68
+	CompositeUPCE11                           CompositeLinear = 69
+	CompositeGS1DataBarOmnidirectional        CompositeLinear = 70
+	CompositeGS1DataBarTruncated              CompositeLinear = 71
+	CompositeGS1DataBarStacked                CompositeLinear = 72
+	CompositeGS1DataBarStackedOmnidirectional CompositeLinear = 73
+	CompositeGS1DataBarLimited                CompositeLinear = 74
+	CompositeGS1DataBarExpanded               CompositeLinear = 75
+	CompositeGS1DataBarExpandedStacked        CompositeLinear = 76
+	CompositeGS1128                           CompositeLinear = 77
+)
+
+// CompositeOptions are the options used when printing a composite symbol. The
+// data passed to Symbol is the 2D component, the linear component is supplied
+// in the options.
+type CompositeOptions struct {
+	// Linear is the linear component type. Defaults to CompositeEAN13.
+	Linear CompositeLinear
+
+	// LinearData is the linear component data.
+	LinearData string
+
+	// ModuleWidth is the module width, in dots (2-8). Defaults to 2.
+	ModuleWidth int
+
+	// MaxWidth is the maximum width of a GS1 DataBar Expanded Stacked linear
+	// component, in dots (106-3280), or 0 for no limit.
+	MaxWidth int
+
+	// HRIFont is the font of the HRI characters.
+	HRIFont HRIFont
+
+	// NoHRI leaves out the HRI characters.
+	NoHRI bool
+
+	// CCC selects the CC-C 2D component, which is only valid with a GS1-128
+	// linear component. Otherwise CC-A or CC-B is selected automatically.
+	CCC bool
+}
+
+func (o *CompositeOptions) symbolType() SymbolType {
+	return SymbolComposite
+}
+
+func (o *CompositeOptions) functions(data string) ([]symbolFunc, error) {
+	var opts CompositeOptions
+	if o != nil {
+		opts = *o
+	}
+	if opts.Linear == 0 {
+		opts.Linear = CompositeEAN13
+	}
+	if opts.ModuleWidth == 0 {
+		opts.ModuleWidth = 2
+	}
+
+	if err := checkData(SymbolComposite, data, 2361); err != nil {
+		return nil, err
+	}
+	if len(opts.LinearData) == 0 {
+		return nil, errors.New("composite symbol linear component data is empty")
+	}
+	if opts.Linear < CompositeEAN8 || opts.Linear > CompositeGS1128 {
+		return nil, errors.New("invalid composite symbol linear component type")
+	}
+	if opts.CCC && opts.Linear != CompositeGS1128 {
+		return nil, errors.New("CC-C requires a GS1-128 linear component")
+	}
+	if err := checkRange("composite symbol module width", opts.ModuleWidth, 2, 8); err != nil {
+		return nil, err
+	}
+	if opts.MaxWidth != 0 {
+		if err := checkRange("composite symbol maximum width", opts.MaxWidth, 106, 3280); err != nil {
+			return nil, err
+		}
+	}
+	if opts.HRIFont > HRIFontC {
+		return nil, errors.New("invalid composite symbol HRI font")
+	}
+
+	component := byte(65)
+	if opts.CCC {
+		component = 66
+	}
+
+	// function 72 numbers the fonts from 1, leaving 0 for no HRI characters
+	hri := byte(opts.HRIFont) + 1
+	if opts.NoHRI {
+		hri = 0
+	}
+
+	return []symbolFunc{
+		{67, []byte{byte(opts.ModuleWidth)}},
+		{71, intLowHigh(opts.MaxWidth, 2)},
+		{72, []byte{hri}},
+		storeData(opts.LinearData, 65, byte(opts.Linear)),
+		storeData(data, 66, component),
+	}, nil
+}
+
+// AztecOptions are the options used when printing an Aztec Code symbol.
+type AztecOptions struct {
+	// Compact selects the compact mode instead of the full-range mode.
+	Compact bool
+
+	// Layers is the number of data layers (1-4 compact, 4-32 full-range), or
+	// 0 for automatic.
+	Layers int
+
+	// ModuleSize is the module size, in dots (2-16). Defaults to 3.
+	ModuleSize int
+
+	// ErrorCorrection is the error correction level, in percent (5-95).
+	// Defaults to 23.
+	ErrorCorrection int
+}
+
+func (o *AztecOptions) symbolType() SymbolType {
+	return SymbolAztec
+}
+
+func (o *AztecOptions) functions(data string) ([]symbolFunc, error) {
+	var opts AztecOptions
+	if o != nil {
+		opts = *o
+	}
+	if opts.ModuleSize == 0 {
+		opts.ModuleSize = 3
+	}
+	if opts.ErrorCorrection == 0 {
+		opts.ErrorCorrection = 23
+	}
+
+	if err := checkData(SymbolAztec, data, 3832); err != nil {
+		return nil, err
+	}
+	mode := byte(0)
+	if opts.Compact {
+		mode = 1
+		if opts.Layers != 0 {
+			if err := checkRange("Aztec Code compact layers", opts.Layers, 1, 4); err != nil {
+				return nil, err
+			}
+		}
+	} else if opts.Layers != 0 {
+		if err := checkRange("Aztec Code full-range layers", opts.Layers, 4, 32); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkRange("Aztec Code module size", opts.ModuleSize, 2, 16); err != nil {
+		return nil, err
+	}
+	if err := checkRange("Aztec Code error correction", opts.ErrorCorrection, 5, 95); err != nil {
+		return nil, err
+	}
+
+	return []symbolFunc{
+		{66, []byte{mode, byte(opts.Layers)}},
+		{67, []byte{byte(opts.ModuleSize)}},
+		{69, []byte{byte(opts.ErrorCorrection)}},
+		storeData(data),
+	}, nil
+}
+
+// DataMatrixOptions are the options used when printing a DataMatrix symbol.
+type DataMatrixOptions struct {
+	// Rectangle selects a rectangular symbol instead of a square one.
+	Rectangle bool
+
+	// Columns is the number of columns, or 0 for automatic.
+	Columns int
+
+	// Rows is the number of rows, or 0 for automatic.
+	Rows int
+
+	// ModuleSize is the module size, in dots (2-16). Defaults to 3.
+	ModuleSize int
+}
+
+func (o *DataMatrixOptions) symbolType() SymbolType {
+	return SymbolDataMatrix
+}
+
+func (o *DataMatrixOptions) functions(data string) ([]symbolFunc, error) {
+	var opts DataMatrixOptions
+	if o != nil {
+		opts = *o
+	}
+	if opts.ModuleSize == 0 {
+		opts.ModuleSize = 3
+	}
+
+	if err := checkData(SymbolDataMatrix, data, 3116); err != nil {
+		return nil, err
+	}
+	if err := checkRange("DataMatrix columns", opts.Columns, 0, 144); err != nil {
+		return nil, err
+	}
+	if err := checkRange("DataMatrix rows", opts.Rows, 0, 144); err != nil {
+		return nil, err
+	}
+	if err := checkRange("DataMatrix module size", opts.ModuleSize, 2, 16); err != nil {
+		return nil, err
+	}
+
+	shape := byte(0)
+	if opts.Rectangle {
+		shape = 1
+	}
+
+	return []symbolFunc{
+		{66, []byte{shape, byte(opts.Columns), byte(opts.Rows)}},
+		{67, []byte{byte(opts.ModuleSize)}},
+		storeData(data),
+	}, nil
+}
+
+// symbolTypes are the ePOS-Print symbol types, mapped to the options used to
+// print them.
+var symbolTypes = map[string]func() SymbolOptions{
+	"qrcode_model_1":      func() SymbolOptions { return &QROptions{Model: QRModel1} },
+	"qrcode_model_2":      func() SymbolOptions { return &QROptions{Model: QRModel2} },
+	"qrcode_micro":        func() SymbolOptions { return &QROptions{Model: QRModelMicro} },
+	"pdf417_standard":     func() SymbolOptions { return &PDF417Options{} },
+	"pdf417_truncated":    func() SymbolOptions { return &PDF417Options{Truncated: true} },
+	"maxicode_mode_2":     func() SymbolOptions { return &MaxiCodeOptions{Mode: 2} },
+	"maxicode_mode_3":     func() SymbolOptions { return &MaxiCodeOptions{Mode: 3} },
+	"maxicode_mode_4":     func() SymbolOptions { return &MaxiCodeOptions{Mode: 4} },
+	"maxicode_mode_5":     func() SymbolOptions { return &MaxiCodeOptions{Mode: 5} },
+	"maxicode_mode_6":     func() SymbolOptions { return &MaxiCodeOptions{Mode: 6} },
+	"gs1_databar_stacked": func() SymbolOptions { return &GS1DataBarOptions{Type: GS1DataBarStacked} },
+	"gs1_databar_stacked_omnidirectional": func() SymbolOptions {
+		return &GS1DataBarOptions{Type: GS1DataBarStackedOmnidirectional}
+	},
+	"gs1_databar_expanded_stacked": func() SymbolOptions {
+		return &GS1DataBarOptions{Type: GS1DataBarExpandedStacked}
+	},
+	"azteccode_fullrange":     func() SymbolOptions { return &AztecOptions{} },
+	"azteccode_compact":       func() SymbolOptions { return &AztecOptions{Compact: true} },
+	"datamatrix_square":       func() SymbolOptions { return &DataMatrixOptions{} },
+	"datamatrix_rectangle_8":  func() SymbolOptions { return &DataMatrixOptions{Rectangle: true, Rows: 8} },
+	"datamatrix_rectangle_12": func() SymbolOptions { return &DataMatrixOptions{Rectangle: true, Rows: 12} },
+	"datamatrix_rectangle_16": func() SymbolOptions { return &DataMatrixOptions{Rectangle: true, Rows: 16} },
+}
+
+// writeSymbol writes an ePOS-Print symbol element using the supplied params.
+func (p *Printer) writeSymbol(params map[string]string, data string) error {
+	typ := params["type"]
+
+	f, ok := symbolTypes[typ]
+	if !ok {
+		return errors.New("unsupported symbol type: " + typ)
+	}
+	opts := f()
+
+	// error correction level
+	level, hasLevel := params["level"]
+	if level == "level_default" {
+		hasLevel = false
+	}
+
+	// module width, height and symbol size
+	var width, height, size int
+	for name, v := range map[string]*int{"width": &width, "height": &height, "size": &size} {
+		if s, ok := params[name]; ok {
+			i, err := strconv.Atoi(s)
+			if err != nil {
+				return err
+			}
+			*v = i
+		}
+	}
+
+	switch o := opts.(type) {
+	case *QROptions:
+		if hasLevel {
+			ecc, ok := qrLevels[level]
+			if !ok {
+				return errors.New("invalid symbol level: " + level)
+			}
+			o.ErrorCorrection = ecc
+		}
+		o.ModuleSize = width
+
+	case *PDF417Options:
+		if hasLevel {
+			var n int
+			if _, err := fmt.Sscanf(level, "level_%d", &n); err != nil || n < 0 || n > 8 {
+				return errors.New("invalid symbol level: " + level)
+			}
+			o.ErrorCorrection = PDF417Level0 + PDF417ErrorCorrection(n)
+		}
+		o.ModuleWidth, o.RowHeight, o.Columns = width, height, size
+
+	case *GS1DataBarOptions:
+		o.ModuleWidth, o.MaxWidth = width, size
+
+	case *AztecOptions:
+		if hasLevel {
+			n, err := strconv.Atoi(level)
+			if err != nil {
+				return errors.New("invalid symbol level: " + level)
+			}
+			o.ErrorCorrection = n
+		}
+		o.ModuleSize = width
+
+	case *DataMatrixOptions:
+		o.ModuleSize = width
+	}
+
+	// send alignment to printer
+	if align, ok := params["align"]; ok {
+		if err := p.SetAlign(align); err != nil {
+			return err
+		}
+	}
+
+	return p.Symbol(opts.symbolType(), data, opts)
+}
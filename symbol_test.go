@@ -0,0 +1,190 @@
+package escpos
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSymbol(t *testing.T) {
+	testCases := []struct {
+		name     string
+		kind     SymbolType
+		data     string
+		opts     SymbolOptions
+		expected string
+	}{
+		{
+			name: "PDF417",
+			kind: SymbolPDF417,
+			data: "abc",
+			opts: &PDF417Options{Columns: 4, ErrorCorrection: PDF417Level5, Truncated: true},
+			expected: "" +
+				"\x1d(k\x03\x000A\x04" +
+				"\x1d(k\x03\x000B\x00" +
+				"\x1d(k\x03\x000C\x03" +
+				"\x1d(k\x03\x000D\x03" +
+				"\x1d(k\x04\x000E05" +
+				"\x1d(k\x03\x000F\x01" +
+				"\x1d(k\x06\x000P0abc" +
+				"\x1d(k\x03\x000Q0",
+		},
+		{
+			name: "MaxiCode",
+			kind: SymbolMaxiCode,
+			data: "abc",
+			opts: &MaxiCodeOptions{Mode: 4},
+			expected: "" +
+				"\x1d(k\x03\x002A4" +
+				"\x1d(k\x06\x002P0abc" +
+				"\x1d(k\x03\x002Q0",
+		},
+		{
+			name: "GS1 DataBar",
+			kind: SymbolGS1DataBar,
+			data: "0123456789012",
+			expected: "" +
+				"\x1d(k\x03\x003C\x02" +
+				"\x1d(k\x04\x003G\x00\x00" +
+				"\x1d(k\x11\x003P0H0123456789012" +
+				"\x1d(k\x03\x003Q0",
+		},
+		{
+			name: "Composite",
+			kind: SymbolComposite,
+			data: "99",
+			opts: &CompositeOptions{Linear: CompositeGS1128, LinearData: "12", CCC: true},
+			expected: "" +
+				"\x1d(k\x03\x004C\x02" +
+				"\x1d(k\x04\x004G\x00\x00" +
+				"\x1d(k\x03\x004H\x01" +
+				"\x1d(k\x07\x004P0AM12" +
+				"\x1d(k\x07\x004P0BB99" +
+				"\x1d(k\x03\x004Q0",
+		},
+		{
+			name: "Composite HRI font",
+			kind: SymbolComposite,
+			data: "99",
+			opts: &CompositeOptions{LinearData: "4901234567894", HRIFont: HRIFontB},
+			expected: "" +
+				"\x1d(k\x03\x004C\x02" +
+				"\x1d(k\x04\x004G\x00\x00" +
+				"\x1d(k\x03\x004H\x02" +
+				"\x1d(k\x12\x004P0AB4901234567894" +
+				"\x1d(k\x07\x004P0BA99" +
+				"\x1d(k\x03\x004Q0",
+		},
+		{
+			name: "Composite without HRI",
+			kind: SymbolComposite,
+			data: "99",
+			opts: &CompositeOptions{LinearData: "4901234567894", NoHRI: true},
+			expected: "" +
+				"\x1d(k\x03\x004C\x02" +
+				"\x1d(k\x04\x004G\x00\x00" +
+				"\x1d(k\x03\x004H\x00" +
+				"\x1d(k\x12\x004P0AB4901234567894" +
+				"\x1d(k\x07\x004P0BA99" +
+				"\x1d(k\x03\x004Q0",
+		},
+		{
+			name: "Aztec",
+			kind: SymbolAztec,
+			data: "abc",
+			opts: &AztecOptions{Compact: true, Layers: 2},
+			expected: "" +
+				"\x1d(k\x04\x005B\x01\x02" +
+				"\x1d(k\x03\x005C\x03" +
+				"\x1d(k\x03\x005E\x17" +
+				"\x1d(k\x06\x005P0abc" +
+				"\x1d(k\x03\x005Q0",
+		},
+		{
+			name: "DataMatrix",
+			kind: SymbolDataMatrix,
+			data: "abc",
+			opts: &DataMatrixOptions{Rectangle: true, Rows: 8},
+			expected: "" +
+				"\x1d(k\x05\x006B\x01\x00\x08" +
+				"\x1d(k\x03\x006C\x03" +
+				"\x1d(k\x06\x006P0abc" +
+				"\x1d(k\x03\x006Q0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewMockWriter()
+			p, _ := NewPrinter(w)
+			if err := p.Symbol(tc.kind, tc.data, tc.opts); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := string(w.GetWritten()); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSymbolErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		kind SymbolType
+		data string
+		opts SymbolOptions
+	}{
+		{"Mismatched options", SymbolPDF417, "abc", &QROptions{}},
+		{"Unknown type", SymbolType(0), "abc", nil},
+		{"Empty data", SymbolMaxiCode, "", nil},
+		{"PDF417 rows", SymbolPDF417, "abc", &PDF417Options{Rows: 2}},
+		{"MaxiCode mode", SymbolMaxiCode, "abc", &MaxiCodeOptions{Mode: 7}},
+		{"GS1 DataBar digits", SymbolGS1DataBar, "abc", nil},
+		{"Composite linear data", SymbolComposite, "abc", nil},
+		{"Aztec layers", SymbolAztec, "abc", &AztecOptions{Compact: true, Layers: 5}},
+		{"DataMatrix module size", SymbolDataMatrix, "abc", &DataMatrixOptions{ModuleSize: 20}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewMockWriter()
+			p, _ := NewPrinter(w)
+			if err := p.Symbol(tc.kind, tc.data, tc.opts); err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if len(w.GetWritten()) != 0 {
+				t.Errorf("Expected nothing written, got %q", w.GetWritten())
+			}
+		})
+	}
+}
+
+func TestWriteNodeSymbolTypes(t *testing.T) {
+	testCases := []struct {
+		params   map[string]string
+		expected string
+	}{
+		{map[string]string{"type": "pdf417_standard", "level": "level_3", "width": "4"}, "\x1d(k\x04\x000E03"},
+		{map[string]string{"type": "maxicode_mode_3"}, "\x1d(k\x03\x002A3"},
+		{map[string]string{"type": "datamatrix_square", "width": "5"}, "\x1d(k\x03\x006C\x05"},
+		{map[string]string{"type": "azteccode_fullrange"}, "\x1d(k\x04\x005B\x00\x00"},
+		{map[string]string{"type": "azteccode_compact", "level": "50"}, "\x1d(k\x04\x005B\x01\x00\x1d(k\x03\x005C\x03\x1d(k\x03\x005E2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.params["type"], func(t *testing.T) {
+			w := NewMockWriter()
+			p, _ := NewPrinter(w)
+			if err := p.WriteNode("symbol", tc.params, "1234"); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !bytes.Contains(w.GetWritten(), []byte(tc.expected)) {
+				t.Errorf("Expected %q in %q", tc.expected, w.GetWritten())
+			}
+		})
+	}
+
+	p, _ := NewPrinter(NewMockWriter())
+	if err := p.WriteNode("symbol", map[string]string{"type": "bogus"}, "1234"); err == nil {
+		t.Error("Expected error for unsupported symbol type")
+	}
+}
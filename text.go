@@ -0,0 +1,264 @@
+package escpos
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"io/ioutil"
+	"math"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// TextAlign is the horizontal alignment of text rendered as an image.
+type TextAlign int
+
+// Text alignments.
+const (
+	// TextAlignStart aligns each paragraph to its starting edge: left for
+	// left-to-right text and right for right-to-left text.
+	TextAlignStart TextAlign = iota
+	TextAlignLeft
+	TextAlignCenter
+	TextAlignRight
+)
+
+// RenderOptions are the options used to render text to an image.
+type RenderOptions struct {
+	// DPI is the resolution in dots per inch.
+	DPI float64
+
+	// FontFile is the path of the TrueType font.
+	FontFile string
+
+	// Hinting is the font hinting.
+	Hinting font.Hinting
+
+	// FontSize is the font size in points.
+	FontSize float64
+
+	// Spacing is the line spacing (e.g. 2 means double spaced).
+	Spacing float64
+
+	// WhiteOnBlack draws white text on a black background.
+	WhiteOnBlack bool
+
+	// Width is the width of the image in dots, to which text is wrapped.
+	Width int
+
+	// ImageHeight is the minimum height of the image in dots. The image is
+	// otherwise as high as the text.
+	ImageHeight int
+
+	// Align is the horizontal alignment of the lines of text.
+	Align TextAlign
+}
+
+// DefaultRenderOptions returns the default text rendering options.
+func DefaultRenderOptions() RenderOptions {
+	return RenderOptions{
+		DPI:          50,
+		FontFile:     "/usr/share/fonts/truetype/dejavu/DejaVuSansMono-Bold.ttf",
+		Hinting:      font.HintingNone,
+		FontSize:     30,
+		Spacing:      1.5,
+		WhiteOnBlack: true,
+		Width:        defaultMaxWidth,
+	}
+}
+
+// validate checks the render options.
+func (o RenderOptions) validate() error {
+	switch {
+	case o.DPI <= 0:
+		return errors.New("render options: dpi must be positive")
+	case o.FontSize <= 0:
+		return errors.New("render options: font size must be positive")
+	case o.Spacing <= 0:
+		return errors.New("render options: spacing must be positive")
+	case o.Width <= 0:
+		return errors.New("render options: width must be positive")
+	case o.ImageHeight < 0:
+		return errors.New("render options: image height must not be negative")
+	}
+	return nil
+}
+
+// fonts caches the parsed fonts, keyed by path.
+var fonts = struct {
+	sync.Mutex
+	m map[string]*truetype.Font
+}{m: make(map[string]*truetype.Font)}
+
+// loadFont returns the TrueType font at path, reading and parsing it only
+// the first time it is used.
+func loadFont(path string) (*truetype.Font, error) {
+	fonts.Lock()
+	defer fonts.Unlock()
+
+	if f, ok := fonts.m[path]; ok {
+		return f, nil
+	}
+
+	fontBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := truetype.Parse(fontBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	fonts.m[path] = f
+	return f, nil
+}
+
+// textLine is a laid out line of text, in visual order.
+type textLine struct {
+	text string
+	rtl  bool
+}
+
+// layoutText breaks text into lines no wider than width, as measured by
+// measure. Each line of text is a paragraph, which is shaped, wrapped and then
+// reordered into visual order.
+func layoutText(text string, width int, measure func(string) int) []textLine {
+	var lines []textLine
+	for _, para := range strings.Split(text, "\n") {
+		rtl := isRTL(para)
+		for _, line := range wrapLine(shapeArabic(para), width, measure) {
+			lines = append(lines, textLine{visualLine(line, rtl), rtl})
+		}
+	}
+	return lines
+}
+
+// wrapLine breaks the paragraph s between words into lines no wider than
+// width. Words wider than a line are broken between characters, and runs of
+// spaces are collapsed.
+func wrapLine(s string, width int, measure func(string) int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		if line != "" {
+			if measure(line+" "+word) <= width {
+				line += " " + word
+				continue
+			}
+			lines = append(lines, line)
+		}
+
+		for measure(word) > width {
+			n := fitRunes(word, width, measure)
+			lines = append(lines, word[:n])
+			word = word[n:]
+		}
+		line = word
+	}
+	return append(lines, line)
+}
+
+// fitRunes returns the length of the longest prefix of s no wider than
+// width, which is at least one rune.
+func fitRunes(s string, width int, measure func(string) int) int {
+	n := 0
+	for i, r := range s {
+		end := i + utf8.RuneLen(r)
+		if n > 0 && measure(s[:end]) > width {
+			break
+		}
+		n = end
+	}
+	return n
+}
+
+// TextRenderer renders text to images.
+type TextRenderer struct {
+	opts RenderOptions
+}
+
+// NewTextRenderer creates a new text renderer using opts.
+func NewTextRenderer(opts RenderOptions) (*TextRenderer, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return &TextRenderer{opts: opts}, nil
+}
+
+// Options returns the options of the renderer.
+func (r *TextRenderer) Options() RenderOptions {
+	return r.opts
+}
+
+// Render draws text to a new image as wide as the render options, wrapping
+// lines to fit and sizing the image to the text.
+func (r *TextRenderer) Render(text string) (*image.RGBA, error) {
+	if err := r.opts.validate(); err != nil {
+		return nil, err
+	}
+
+	f, err := loadFont(r.opts.FontFile)
+	if err != nil {
+		return nil, err
+	}
+	face := truetype.NewFace(f, &truetype.Options{
+		Size:    r.opts.FontSize,
+		DPI:     r.opts.DPI,
+		Hinting: r.opts.Hinting,
+	})
+	defer face.Close()
+
+	measure := func(s string) int {
+		return font.MeasureString(face, s).Ceil()
+	}
+	lines := layoutText(text, r.opts.Width, measure)
+
+	// size the image to the lines
+	metrics := face.Metrics()
+	ascent, descent := metrics.Ascent.Ceil(), metrics.Descent.Ceil()
+	lineHeight := int(math.Ceil(r.opts.FontSize * r.opts.DPI / 72 * r.opts.Spacing))
+	height := ascent + descent + (len(lines)-1)*lineHeight
+	if height < r.opts.ImageHeight {
+		height = r.opts.ImageHeight
+	}
+
+	fg, bg := image.Black, image.White
+	if r.opts.WhiteOnBlack {
+		fg, bg = image.White, image.Black
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, r.opts.Width, height))
+	draw.Draw(rgba, rgba.Bounds(), bg, image.ZP, draw.Src)
+
+	d := &font.Drawer{
+		Dst:  rgba,
+		Src:  fg,
+		Face: face,
+	}
+	for i, line := range lines {
+		x := r.lineX(measure(line.text), line.rtl)
+		d.Dot = fixed.P(x, ascent+i*lineHeight)
+		d.DrawString(line.text)
+	}
+
+	return rgba, nil
+}
+
+// lineX returns the x position of a line of text w dots wide.
+func (r *TextRenderer) lineX(w int, rtl bool) int {
+	switch r.opts.Align {
+	case TextAlignCenter:
+		return (r.opts.Width - w) / 2
+	case TextAlignRight:
+		return r.opts.Width - w
+	case TextAlignStart:
+		if rtl {
+			return r.opts.Width - w
+		}
+	}
+	return 0
+}
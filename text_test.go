@@ -0,0 +1,149 @@
+package escpos
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestPrinterRenderOptions(t *testing.T) {
+	a, _ := NewPrinter(NewMockWriter())
+	b, _ := NewPrinter(NewMockWriter())
+
+	a.SetDPI(203)
+	a.SetFontFile("/tmp/font.ttf")
+	a.SetHinting("full")
+	a.SetImageHight(64)
+
+	opts := a.TextRenderer().Options()
+	if opts.DPI != 203 || opts.FontFile != "/tmp/font.ttf" || opts.Hinting != font.HintingFull || opts.ImageHeight != 64 {
+		t.Errorf("Unexpected options %+v", opts)
+	}
+	if got := b.TextRenderer().Options(); got != DefaultRenderOptions() {
+		t.Errorf("Expected default options on other printer, got %+v", got)
+	}
+}
+
+func TestWithRenderOptions(t *testing.T) {
+	opts := DefaultRenderOptions()
+	opts.FontSize = 12
+	opts.WhiteOnBlack = false
+
+	p, err := NewPrinter(NewMockWriter(), WithRenderOptions(opts))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := p.TextRenderer().Options(); got != opts {
+		t.Errorf("Expected %+v, got %+v", opts, got)
+	}
+
+	opts.DPI = 0
+	if _, err := NewPrinter(NewMockWriter(), WithRenderOptions(opts)); err == nil {
+		t.Error("Expected error for invalid render options")
+	}
+}
+
+func TestWrapLine(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		width    int
+		expected []string
+	}{
+		{"Fits", "hello world", 20, []string{"hello world"}},
+		{"Word wrap", "hello world", 5, []string{"hello", "world"}},
+		{"Several words", "a b c d e", 3, []string{"a b", "c d", "e"}},
+		{"Long word", "abcdefgh ij", 3, []string{"abc", "def", "gh", "ij"}},
+		{"Collapsed spaces", "  a   b  ", 10, []string{"a b"}},
+		{"Empty", "", 10, []string{""}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := wrapLine(tc.text, tc.width, utf8.RuneCountInString)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestLayoutText(t *testing.T) {
+	lines := layoutText("سلام 10\nabc def", 4, utf8.RuneCountInString)
+	expected := []textLine{
+		{"ﻡﻼﺳ", true},
+		{"10", true},
+		{"abc", false},
+		{"def", false},
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, lines)
+	}
+}
+
+func TestTextRendererLineX(t *testing.T) {
+	testCases := []struct {
+		align    TextAlign
+		rtl      bool
+		expected int
+	}{
+		{TextAlignStart, false, 0},
+		{TextAlignStart, true, 60},
+		{TextAlignLeft, true, 0},
+		{TextAlignCenter, false, 30},
+		{TextAlignRight, false, 60},
+	}
+
+	for _, tc := range testCases {
+		r := &TextRenderer{opts: RenderOptions{Width: 100, Align: tc.align}}
+		if got := r.lineX(40, tc.rtl); got != tc.expected {
+			t.Errorf("align %d rtl %t: expected %d, got %d", tc.align, tc.rtl, tc.expected, got)
+		}
+	}
+}
+
+func TestTextRendererRender(t *testing.T) {
+	dir, err := ioutil.TempDir("", "escpos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "goregular.ttf")
+	if err := ioutil.WriteFile(path, goregular.TTF, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultRenderOptions()
+	opts.FontFile = path
+	r, err := NewTextRenderer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	one, err := r.Render("hello")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	three, err := r.Render("hello\nworld\nagain")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if one.Bounds().Dx() != opts.Width || three.Bounds().Dx() != opts.Width {
+		t.Errorf("Expected width %d, got %d and %d", opts.Width, one.Bounds().Dx(), three.Bounds().Dx())
+	}
+	if three.Bounds().Dy() <= one.Bounds().Dy() {
+		t.Errorf("Expected three lines to be higher than one, got %d and %d", three.Bounds().Dy(), one.Bounds().Dy())
+	}
+
+	f, _ := loadFont(path)
+	if g, _ := loadFont(path); f != g {
+		t.Error("Expected cached font")
+	}
+}
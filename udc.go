@@ -0,0 +1,112 @@
+package escpos
+
+import (
+	"errors"
+	"image"
+	"sort"
+)
+
+// User-defined characters are the size of font A, and are assigned the codes
+// of the printable ASCII characters.
+const (
+	userCharHeight   = 24
+	userCharMaxWidth = 12
+	userCharFirst    = 0x20
+	userCharLast     = 0x7e
+)
+
+// DefineUserChars defines the glyphs, converted to rasters with opts, as
+// user-defined characters (ESC &), and maps their runes to them so that text
+// written with Text and WriteText prints them inline. The characters are
+// assigned codes from 32 in rune order, and replace any previously defined
+// characters. Glyphs are at most 12 dots wide, wider ones being scaled down
+// unless opts fit them otherwise, and 24 dots high.
+func (p *Printer) DefineUserChars(glyphs map[rune]image.Image, opts *ImageOptions) error {
+	if len(glyphs) == 0 || len(glyphs) > userCharLast-userCharFirst+1 {
+		return errors.New("user-defined characters must number 1 to 95")
+	}
+	o := p.imageOptions(opts)
+	conv := o.Converter
+	conv.MaxWidth = userCharMaxWidth
+
+	runes := make([]rune, 0, len(glyphs))
+	for r := range glyphs {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+
+	chars := make(map[rune]byte, len(runes))
+	buf := []byte{0x1b, '&', userCharHeight / 8, userCharFirst, byte(userCharFirst + len(runes) - 1)}
+	for i, r := range runes {
+		data, width, lineWidth := conv.ToRaster(glyphs[r])
+		height := rasterSize(data, lineWidth)
+
+		buf = append(buf, byte(width))
+		buf = append(buf, toColumns(data, lineWidth, width, height, width, userCharHeight/8)...)
+		chars[r] = byte(userCharFirst + i)
+	}
+
+	if err := p.write(buf); err != nil {
+		return err
+	}
+	p.userChars = chars
+	return nil
+}
+
+// ClearUserChars deletes the user-defined characters (ESC ?) and unmaps their
+// runes.
+func (p *Printer) ClearUserChars() error {
+	codes := make([]int, 0, len(p.userChars))
+	for _, c := range p.userChars {
+		codes = append(codes, int(c))
+	}
+	sort.Ints(codes)
+
+	p.userChars = nil
+	for _, c := range codes {
+		if err := p.write([]byte{0x1b, '?', byte(c)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StoreDownloadGraphics stores img, converted to a raster with opts, in the
+// download graphics memory of the printer with the key code key (GS ( L
+// function 83). Download graphics are kept in RAM until the printer is
+// turned off, and are suited to images defined once per session.
+func (p *Printer) StoreDownloadGraphics(key NVKey, img image.Image, opts *ImageOptions) error {
+	o := p.imageOptions(opts)
+
+	data, width, lineWidth := o.Converter.ToRaster(img)
+	return p.StoreDownloadGraphicsRaster(key, width, rasterSize(data, lineWidth), data)
+}
+
+// StoreDownloadGraphicsRaster stores the width×height raster planes, one for
+// each colour from the first, in the download graphics memory of the printer
+// with the key code key (GS ( L function 83).
+func (p *Printer) StoreDownloadGraphicsRaster(key NVKey, width, height int, planes ...[]byte) error {
+	data, err := graphicsDefinition(key, width, height, planes)
+	if err != nil {
+		return err
+	}
+	return p.nvSend('S', data)
+}
+
+// PrintDownloadGraphics prints the download graphics with the key code key
+// (GS ( L function 85).
+func (p *Printer) PrintDownloadGraphics(key NVKey) error {
+	return p.nvSend('U', []byte{key[0], key[1], 1, 1})
+}
+
+// DeleteDownloadGraphics deletes the download graphics with the key code key
+// (GS ( L function 82).
+func (p *Printer) DeleteDownloadGraphics(key NVKey) error {
+	return p.nvSend('R', []byte{key[0], key[1]})
+}
+
+// DeleteAllDownloadGraphics deletes all the download graphics (GS ( L
+// function 81).
+func (p *Printer) DeleteAllDownloadGraphics() error {
+	return p.nvSend('Q', []byte("CLR"))
+}
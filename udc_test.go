@@ -0,0 +1,83 @@
+package escpos
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestUserChars(t *testing.T) {
+	// a 2 dot wide glyph with the top-left dot set, and a 1 dot wide glyph
+	// with its bottom dot set
+	a := image.NewGray(image.Rect(0, 0, 2, 1))
+	a.SetGray(0, 0, color.Gray{0xff})
+	b := image.NewGray(image.Rect(0, 0, 1, 24))
+	b.SetGray(0, 23, color.Gray{0xff})
+
+	w := NewMockWriter()
+	p, _ := NewPrinter(w)
+	if err := p.DefineUserChars(map[rune]image.Image{'★': b, '☆': a}, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "\x1b&\x03\x20\x21" +
+		"\x01\x00\x00\x01" +
+		"\x02\x80\x00\x00\x00\x00\x00"
+	if got := string(w.GetWritten()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	w.Reset()
+	if err := p.Text(map[string]string{}, "a★☆b★"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected = "a\x1b%\x01\x20\x21\x1b%\x00b\x1b%\x01\x20\x1b%\x00"
+	if got := string(w.GetWritten()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	w.Reset()
+	if err := p.ClearUserChars(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, exp := string(w.GetWritten()), "\x1b?\x20\x1b?\x21"; got != exp {
+		t.Errorf("Expected %q, got %q", exp, got)
+	}
+	if len(p.userChars) != 0 {
+		t.Error("Expected user characters to be unmapped")
+	}
+
+	if err := p.DefineUserChars(nil, nil); err == nil {
+		t.Error("Expected error without glyphs")
+	}
+}
+
+func TestDownloadGraphics(t *testing.T) {
+	key := NVKey{'A', '1'}
+	testCases := []struct {
+		name     string
+		fn       func(p *Printer) error
+		expected string
+	}{
+		{"Store", func(p *Printer) error { return p.StoreDownloadGraphicsRaster(key, 8, 1, []byte{0xf0}) },
+			"\x1d(L\x0c\x000S0A1\x01\x08\x00\x01\x001\xf0"},
+		{"Print", func(p *Printer) error { return p.PrintDownloadGraphics(key) },
+			"\x1d(L\x06\x000UA1\x01\x01"},
+		{"Delete", func(p *Printer) error { return p.DeleteDownloadGraphics(key) },
+			"\x1d(L\x04\x000RA1"},
+		{"Delete all", func(p *Printer) error { return p.DeleteAllDownloadGraphics() },
+			"\x1d(L\x05\x000QCLR"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewMockWriter()
+			p, _ := NewPrinter(w)
+			if err := tc.fn(p); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := string(w.GetWritten()); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -35,9 +35,15 @@ func (n *Node) Attributes() map[string]string {
 // SOAP envelope structure for parsing
 type Envelope struct {
 	XMLName xml.Name `xml:"Envelope"`
+	Header  Header   `xml:"Header"`
 	Body    Body     `xml:"Body"`
 }
 
+// Header is the SOAP header of an ePOS request.
+type Header struct {
+	PrintJobID string `xml:"parameter>printjobid"`
+}
+
 type Body struct {
 	XMLName xml.Name `xml:"Body"`
 	Content []byte   `xml:",innerxml"`
@@ -72,3 +78,13 @@ func getBodyChildren(data []byte) ([]Node, error) {
 
 	return wrapper.Nodes, nil
 }
+
+// getPrintJobID returns the print job ID in the SOAP header of a XML
+// document, or the empty string when it has none.
+func getPrintJobID(data []byte) string {
+	var envelope Envelope
+	if err := xml.Unmarshal(data, &envelope); err != nil {
+		return ""
+	}
+	return envelope.Header.PrintJobID
+}